@@ -0,0 +1,65 @@
+// Command dbcopy is deprecated, it runs dbtools copy with its flags
+// translated.
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/ppreeper/dbtools/internal/forward"
+)
+
+func main() {
+	flag.String("c", "", "config.yml")
+	flag.String("source", "", "source database")
+	flag.String("ss", "", "source schema")
+	flag.String("dest", "", "destination database or file:")
+	flag.String("ds", "", "dest schema")
+	flag.String("table", "", "specific table")
+	flag.Bool("t", false, "gen table sql")
+	flag.String("view", "", "specific view")
+	flag.Bool("v", false, "gen view sql")
+	flag.String("routine", "", "specific routine")
+	flag.Bool("r", false, "gen routine sql")
+	flag.String("index", "", "specific index")
+	flag.Bool("i", false, "gen index sql")
+	flag.Bool("all", false, "all tables")
+	flag.Bool("l", false, "gen table link sql")
+	flag.Bool("u", false, "gen update procedure")
+	flag.String("f", "", "regex filter")
+	flag.Bool("n", false, "no-op debug")
+	flag.Int("j", 8, "job count")
+	timeout := flag.Int("timeout", 10, "query timeout")
+	flag.String("logfile", "dbcopy.log", "log file")
+	flag.Parse()
+
+	args := append([]string{"copy"}, forward.Args(flag.CommandLine, map[string]string{
+		"c":       "config",
+		"source":  "source",
+		"ss":      "source-schema",
+		"dest":    "dest",
+		"ds":      "dest-schema",
+		"table":   "table",
+		"t":       "tables",
+		"view":    "view",
+		"v":       "views",
+		"routine": "routine",
+		"r":       "routines",
+		"index":   "index",
+		"i":       "indexes",
+		"all":     "all",
+		"l":       "link",
+		"u":       "update",
+		"f":       "filter",
+		"n":       "dry-run",
+		"j":       "jobs",
+		"logfile": "logfile",
+	})...)
+	// the timeout was in seconds
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "timeout" {
+			args = append(args, "--timeout="+strconv.Itoa(*timeout)+"s")
+		}
+	})
+	forward.Run("dbcopy", append(args, flag.Args()...))
+}
@@ -0,0 +1,27 @@
+// Command dbq is deprecated, it runs dbtools query with its flags
+// translated.
+package main
+
+import (
+	"flag"
+
+	"github.com/ppreeper/dbtools/internal/forward"
+)
+
+func main() {
+	flag.String("c", "", "config.yml")
+	flag.String("db", "", "database")
+	flag.String("q", "", "sql query")
+	flag.String("f", ";", "field seperator")
+	flag.Bool("t", false, "sql timer")
+	flag.Parse()
+
+	args := append([]string{"query"}, forward.Args(flag.CommandLine, map[string]string{
+		"c":  "config",
+		"db": "db",
+		"q":  "query",
+		"f":  "field-sep",
+		"t":  "timer",
+	})...)
+	forward.Run("dbq", append(args, flag.Args()...))
+}
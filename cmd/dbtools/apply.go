@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/manifest"
+	"github.com/spf13/cobra"
+)
+
+// ApplyConfig apply command settings
+type ApplyConfig struct {
+	Dest     string `mapstructure:"dest"`
+	Debug    bool   `mapstructure:"dry-run"`
+	Continue bool   `mapstructure:"continue"`
+}
+
+func newApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply <archive|dir>",
+		Short: "apply the output of copy to archive: or dir: on a database",
+		Long: `apply the output of copy to archive: or dir: on a database
+
+Every file of the manifest is checked against its checksum before anything
+runs, then the files run in the manifest order: tables and their rows before
+the views that depend on them, indexes last. Each file runs in one
+transaction, a failed file leaves its object as it was. The destination has
+to use the sql dialect of the source the files were generated from.
+
+A file is split into batches for the dialect: mssql at GO lines, the others
+at the semicolons ending each statement. The first failed file stops the run
+and the rest are skipped, --continue runs them anyway.`,
+		Example: `  dbtools apply -d dev prod.tar.gz
+  dbtools apply -d dev schema/prod`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ApplyConfig{}
+			if err := loadConfig(cmd, "apply", &config); err != nil {
+				return err
+			}
+			return runApply(cmd, &config, args[0])
+		},
+	}
+	cmd.Flags().StringP("dest", "d", "", "config host name or connection URI")
+	cmd.Flags().BoolP("dry-run", "n", false, "print the batches of every file instead of running them")
+	cmd.Flags().BoolP("continue", "k", false, "keep going after a failed file")
+	return cmd
+}
+
+func runApply(cmd *cobra.Command, config *ApplyConfig, src string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	dir := src
+	if manifest.IsArchive(src) {
+		tmp, err := os.MkdirTemp("", "dbtools-apply-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		if err := manifest.Extract(src, tmp); err != nil {
+			return err
+		}
+		dir = tmp
+	}
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	if err := m.Verify(dir); err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+
+	if config.Debug {
+		return printBatches(out, m, dir)
+	}
+
+	db, err := openHost(ctx, config.Dest)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if dialect(db.Driver) != dialect(m.Driver) {
+		return fmt.Errorf("%s: %s sql does not run on %s %s", src, m.Driver, hostLabel(config.Dest), db.Driver)
+	}
+
+	var errs []error
+	lines := [][]string{{"FILE", "STATUS", "ERROR"}}
+	for _, f := range m.Files {
+		if len(errs) > 0 && !config.Continue {
+			lines = append(lines, []string{f.Path, "skipped", ""})
+			continue
+		}
+		err := ctx.Err()
+		if err == nil {
+			// importing rows takes as long as it takes, only sql is bounded
+			octx, cancel := context.WithCancel(ctx)
+			if f.Kind != "data" {
+				octx, cancel = timeoutContext(ctx)
+			}
+			err = applyFile(octx, db, dir, f)
+			cancel()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			lines = append(lines, []string{f.Path, "failed", err.Error()})
+			continue
+		}
+		lines = append(lines, []string{f.Path, "ok", ""})
+	}
+	printColumns(out, lines, func(row, col int, v string) string {
+		switch {
+		case row == 0 || col != 1:
+			return v
+		case strings.TrimSpace(v) == "ok":
+			return okStyle.Render(v)
+		case strings.TrimSpace(v) == "skipped":
+			return canceledStyle.Render(v)
+		default:
+			return failStyle.Render(v)
+		}
+	})
+	return failed("files", len(m.Files), errs)
+}
+
+// applyFile imports the rows of a data file or runs the batches of a sql
+// file in one transaction
+func applyFile(ctx context.Context, db *database.Database, dir string, f manifest.File) error {
+	fn := filepath.Join(dir, filepath.FromSlash(f.Path))
+	if f.Kind == "data" {
+		r, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = db.ImportTable(ctx, f.Schema, f.Object, r)
+		return err
+	}
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	if n, _, err := db.ExecDDL(ctx, f.Schema+"."+f.Object, database.SplitBatches(db.Driver, string(data))...); err != nil {
+		return fmt.Errorf("batch %d: %w", n+1, err)
+	}
+	return nil
+}
+
+// printBatches prints the batches every sql file of m is split into
+func printBatches(out io.Writer, m *manifest.Manifest, dir string) error {
+	for _, f := range m.Files {
+		fmt.Fprintf(out, "-- %s\n", f.Path)
+		if f.Kind == "data" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return err
+		}
+		printScript(out, m.Driver, string(data))
+	}
+	return nil
+}
+
+// printScript prints the batches of a sql script, mssql ones ended by GO
+func printScript(out io.Writer, driver, script string) {
+	fmt.Fprint(out, database.JoinBatches(driver, database.SplitBatches(driver, script)))
+}
+
+// dialect sql dialect of a driver, the postgres drivers share one
+func dialect(driver string) string {
+	if driver == "pgx" {
+		return "postgres"
+	}
+	return driver
+}
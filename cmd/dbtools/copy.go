@@ -0,0 +1,513 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/str"
+	"github.com/spf13/cobra"
+)
+
+// CopyConfig copy command settings
+type CopyConfig struct {
+	Source      string `mapstructure:"source"`
+	Dest        string `mapstructure:"dest"`
+	SSchemaName string `mapstructure:"source-schema"`
+	DSchemaName string `mapstructure:"dest-schema"`
+	TableName   string `mapstructure:"table"`
+	Table       bool   `mapstructure:"tables"`
+	ViewName    string `mapstructure:"view"`
+	View        bool   `mapstructure:"views"`
+	RoutineName string `mapstructure:"routine"`
+	Routine     bool   `mapstructure:"routines"`
+	IndexName   string `mapstructure:"index"`
+	Index       bool   `mapstructure:"indexes"`
+	FilterDef   string `mapstructure:"filter"`
+	JobCount    int    `mapstructure:"jobs"`
+	Link        bool   `mapstructure:"link"`
+	Debug       bool   `mapstructure:"dry-run"`
+	Update      bool   `mapstructure:"update"`
+	All         bool   `mapstructure:"all"`
+	Report      string `mapstructure:"report"`
+	Backup      string `mapstructure:"backup"`
+	BackupData  bool   `mapstructure:"backup-data"`
+	Plan        string `mapstructure:"plan"`
+	Data        bool   `mapstructure:"data"`
+	Templates   string `mapstructure:"templates"`
+	Rename      string `mapstructure:"rename"`
+	RenameCase  string `mapstructure:"rename-case"`
+
+	RenameSchemas map[string]string `mapstructure:"rename-schema"`
+	RenameTables  map[string]string `mapstructure:"rename-table"`
+	RenameColumns map[string]string `mapstructure:"rename-column"`
+
+	Hooks map[string][]string `mapstructure:"hooks"`
+
+	Filter *regexp.Regexp `mapstructure:"-"`
+
+	report      *copyReport
+	plan        *copyPlan
+	sink        sink
+	backupStamp string
+	templates   *database.Templates
+	hooks       map[string][]string
+	mapping     *database.Mapping
+}
+
+func newCopyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "copy",
+		Short: "copy tables, views, routines and indexes between databases",
+		Long: `copy tables, views, routines and indexes between databases
+
+With --dest dir:<path> the generated sql is written to a tree of files
+instead, <schema>/<kind>/<nnnn>_<name>.sql numbered in replay order, with a
+manifest.json listing every file, its object and sha256 checksum. The tree is
+only written when every object succeeded, files of objects no longer copied
+are removed. file: is short for dir:. (the current directory).
+
+--dest archive:<file> packs the same tree into one .tar.gz, .tgz or .zip
+file, dbtools apply restores it on a host. --data also exports the rows of
+every table to <schema>/data/<nnnn>_<table>.jsonl in the tree or archive.
+
+--dry-run plans the copy without changing anything: every object the copy
+would run, the links and update procedures of a table included, is looked up
+on the destination and listed as created or replaced, a replaced table with
+its estimated rows and the views and foreign keys dropped with it. --plan
+also writes the plan as JSON, - prints only the JSON. With a dir: destination
+--dry-run prints the sql instead.
+
+A summary of every object is printed at the end, --report also writes it to
+a file: JUnit XML for a .xml file, JSON otherwise.
+
+--backup saves the destination definition of every table, view and routine
+before it is replaced to <dir>/<timestamp>/<dest>/, dbtools restore puts them
+back. --backup-data also keeps the table data by moving the old tables to a
+dbtools_backup_<timestamp> schema instead of dropping them.
+
+--templates points at a directory of text/template files overriding the sql
+generated for tables, links and update procedures, named
+<kind>.<dialect>.tmpl with kind table, link or update and dialect postgres or
+mssql. A file only needs to define the templates it changes, "drop",
+"create" or "after", empty by default, that follows a table create. The
+built-in ones are in pkg/database/templates.
+
+Hooks run sql before and after every object, schema and the whole run on a
+destination, set under copy: hooks: in the settings file, see dbtools.yml in
+the README. SET statements only take effect in the object hooks. {dest}, {source_schema}, {schema}, {kind} and {object} are
+replaced in them.
+
+--rename reads rules turning source schema, table and column names into
+destination ones from a YAML file, the --rename-* flags add to them. They
+apply to the tables, their indexes, links and update procedures; views and
+routines keep their names and bodies.`,
+		Example: `  dbtools copy -s prod -d dev --source-schema public --tables
+  dbtools copy -s prod -d dir:schema/prod --all -f '^tmp_'
+  dbtools copy -s prod -d archive:prod.tar.gz --all --data
+  dbtools copy -s prod -d dev --all --report copy-report.xml
+  dbtools copy -s prod -d reporting --views --backup backups
+  dbtools copy -s prod -d dev --all -n --plan plan.json
+  dbtools copy -s erp -d dev --tables --rename-case snake --rename-schema dbo=public`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := CopyConfig{}
+			if err := loadConfig(cmd, "copy", &config); err != nil {
+				return err
+			}
+			return runCopy(cmd, &config)
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringP("source", "s", "", "source host or connection URI")
+	fs.StringP("dest", "d", "", "destination host, group, connection URI, dir:<path> or archive:<file>")
+	fs.String("source-schema", "", "source schema")
+	fs.String("dest-schema", "", "destination schema")
+	fs.BoolP("tables", "t", false, "copy tables")
+	fs.String("table", "", "copy a specific table")
+	fs.BoolP("views", "v", false, "copy views")
+	fs.String("view", "", "copy a specific view")
+	fs.BoolP("routines", "r", false, "copy routines")
+	fs.String("routine", "", "copy a specific routine")
+	fs.BoolP("indexes", "i", false, "copy indexes")
+	fs.String("index", "", "copy a specific index")
+	fs.BoolP("all", "a", false, "copy tables, views and routines")
+	fs.Bool("data", false, "with a dir: or archive: destination, also export the table rows")
+	fs.BoolP("link", "l", false, "create foreign tables linking to the source")
+	fs.BoolP("update", "u", false, "create update procedures")
+	fs.StringP("filter", "f", "", "skip objects matching this regex")
+	fs.BoolP("dry-run", "n", false, "show what would change on the destination")
+	fs.String("plan", "", "with --dry-run, write the plan as JSON to this file, - for stdout")
+	fs.IntP("jobs", "j", 8, "concurrent jobs")
+	fs.String("report", "", "write the results to a JSON or JUnit XML (.xml) file")
+	fs.String("backup", "", "save the replaced destination objects to this directory")
+	fs.Bool("backup-data", false, "with --backup, move replaced tables to a backup schema")
+	fs.String("templates", "", "directory of ddl templates overriding the built-in ones")
+	fs.String("rename", "", "YAML file of rules renaming source identifiers on the destination")
+	fs.String("rename-case", "", "change the case of table and column names: lower, upper or snake")
+	fs.StringToString("rename-schema", nil, "rename a source schema, source=dest")
+	fs.StringToString("rename-table", nil, "rename a source table, source=dest")
+	fs.StringToString("rename-column", nil, "rename a source column, [table.]source=dest")
+	return cmd
+}
+
+func runCopy(cmd *cobra.Command, config *CopyConfig) error {
+	ctx := cmd.Context()
+	var err error
+	config.Filter, err = regexp.CompilePOSIX(config.FilterDef)
+	if err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+
+	// config options display
+	logger.Info("start", "config", config)
+
+	out := cmd.OutOrStdout()
+	if config.Plan == "-" {
+		// only the JSON plan goes to stdout
+		out = io.Discard
+	}
+	fmt.Fprintln(out, str.RJustLen("Source:", 8), str.LJustLen(hostLabel(config.Source), 20), str.RJustLen("SSchemaName:", 13), str.LJustLen(config.SSchemaName, 20))
+	fmt.Fprintln(out, str.RJustLen("Dest:", 8), str.LJustLen(hostLabel(config.Dest), 20), str.RJustLen("DSchemaName:", 13), str.LJustLen(config.DSchemaName, 20))
+	fmt.Fprintln(out, str.RJustLen("Table:", 8), config.Table, str.RJustLen("TableName:", 13), config.TableName)
+	fmt.Fprintln(out, str.RJustLen("View:", 8), config.View, str.RJustLen("ViewName:", 13), config.ViewName)
+	fmt.Fprintln(out, str.RJustLen("Routine:", 8), config.Routine, str.RJustLen("RoutineName:", 13), config.RoutineName)
+	fmt.Fprintln(out, str.RJustLen("Index:", 8), config.Index, str.RJustLen("IndexName:", 13), config.IndexName)
+	fmt.Fprintln(out, str.RJustLen("All:", 8), config.All, str.RJustLen("Link:", 8), config.Link, str.RJustLen("Update:", 8), config.Update, str.RJustLen("Debug:", 8), config.Debug)
+
+	sdbConfig, dests, err := config.getDBConfigs()
+	if err != nil {
+		return err
+	}
+
+	if err := config.checkParams(); err != nil {
+		return err
+	}
+	if err := config.loadHooks(); err != nil {
+		return err
+	}
+	if err := config.loadMapping(); err != nil {
+		return err
+	}
+	if config.Templates != "" {
+		if config.templates, err = database.LoadTemplates(config.Templates); err != nil {
+			return err
+		}
+	}
+
+	sdb, err := database.OpenDatabase(ctx, sdbConfig)
+	if err != nil {
+		return err
+	}
+	defer sdb.Close()
+
+	config.report = newCopyReport(hostLabel(config.Source))
+	config.backupStamp = config.report.Started.Format("20060102150405")
+	if config.Debug && !config.fileDest() {
+		return planCopy(ctx, out, cmd.OutOrStdout(), config, sdb, dests)
+	}
+	err = copyAll(ctx, out, config, sdb, dests)
+	config.report.finish()
+	config.report.print(out)
+	dir := filepath.Join(config.Backup, config.backupStamp)
+	if _, serr := os.Stat(dir); config.Backup != "" && serr == nil {
+		fmt.Fprintf(out, "backups saved to %s, undo with dbtools restore -d <dest> %s%c<dest>\n", dir, dir, filepath.Separator)
+	}
+	if config.Report != "" {
+		if werr := config.report.write(config.Report); werr != nil {
+			return errors.Join(err, werr)
+		}
+	}
+	return err
+}
+
+// planCopy prints what copying to dests would change, --plan also writes it
+// as JSON, to stdout for -
+func planCopy(ctx context.Context, out, stdout io.Writer, config *CopyConfig, sdb *database.Database, dests []string) error {
+	config.plan = newCopyPlan(hostLabel(config.Source), config.BackupData)
+	err := copyAll(ctx, out, config, sdb, dests)
+	config.plan.finish()
+	config.plan.print(out)
+	if config.Plan != "" {
+		if werr := config.plan.write(stdout, config.Plan); werr != nil {
+			return errors.Join(err, werr)
+		}
+	}
+	return err
+}
+
+// copyAll copies the source schemas to every destination
+func copyAll(ctx context.Context, out io.Writer, config *CopyConfig, sdb *database.Database, dests []string) error {
+	// =======
+	// get schemas
+	// =======
+
+	sSchemas, err := lookupSchemas(ctx, sdb, config.SSchemaName)
+	if err != nil {
+		return err
+	}
+	logger.Info("", "schemas", sSchemas)
+
+	if config.fileDest() {
+		return copyFiles(ctx, config, sdb, sSchemas)
+	}
+	for _, dest := range dests {
+		if len(dests) > 1 {
+			fmt.Fprintln(out, "==>", dest)
+		}
+		if err := copyTo(ctx, config, sdb, dest, sSchemas); err != nil {
+			return fmt.Errorf("%s: %w", dest, err)
+		}
+	}
+	return nil
+}
+
+// copyFiles writes the sql of the source schemas to the file destination,
+// in the source dialect. Nothing is written when an object failed.
+func copyFiles(ctx context.Context, config *CopyConfig, sdb *database.Database, sSchemas []database.Schema) error {
+	if config.Debug {
+		return copySchemas(ctx, config, sdb, sdb, sSchemas)
+	}
+	var err error
+	if config.sink, err = config.newSink(sdb.Driver); err != nil {
+		return err
+	}
+	if err := copySchemas(ctx, config, sdb, sdb, sSchemas); err != nil {
+		config.sink.discard()
+		return err
+	}
+	return config.sink.close()
+}
+
+// copyTo copies the source schemas to the destination host
+func copyTo(ctx context.Context, config *CopyConfig, sdb *database.Database, dest string, sSchemas []database.Schema) error {
+	ddb, err := openHost(ctx, dest)
+	if err != nil {
+		return err
+	}
+	defer ddb.Close()
+
+	dSchemas, err := lookupSchemas(ctx, ddb, config.DSchemaName)
+	if err != nil {
+		return err
+	}
+	logger.Info("", "dest", hostLabel(dest), "schemas", dSchemas)
+	if err := config.checkSessionHooks(ddb.Driver); err != nil {
+		return err
+	}
+	v := hookVars{dest: hostLabel(dest)}
+	if err := runHooks(ctx, config, ddb, "before-run", v); err != nil {
+		return err
+	}
+	err = copySchemas(ctx, config, sdb, ddb, sSchemas)
+	if ctx.Err() == nil {
+		err = errors.Join(err, runHooks(ctx, config, ddb, "after-run", v))
+	}
+	return err
+}
+
+// copySchemas copies the selected objects of every schema, the objects that
+// failed are reported once all have been tried
+func copySchemas(ctx context.Context, config *CopyConfig, sdb, ddb *database.Database, sSchemas []database.Schema) error {
+	if !config.fileDest() && config.DSchemaName == "" {
+		var names []string
+		for _, s := range sSchemas {
+			names = append(names, s.Name)
+		}
+		if err := config.mapping.CheckSchemas(names); err != nil {
+			return fmt.Errorf("rename: %w", err)
+		}
+	}
+	total := 0
+	var errs []error
+	for _, s := range sSchemas {
+		logger.Info("", "schema", s)
+		DSchema := s.Name
+		if !config.fileDest() {
+			DSchema = cmp.Or(config.DSchemaName, config.mapping.Schema(s.Name))
+		}
+
+		data := database.Conn{
+			Source:    sdb,
+			Dest:      ddb,
+			SSchema:   s.Name,
+			DSchema:   DSchema,
+			Templates: config.templates,
+			Mapping:   config.mapping,
+		}
+		logger.Info("schemas", "source", data.SSchema, "dest", data.DSchema)
+		v := hookVars{dest: destLabel(config, &data), sourceSchema: data.SSchema, schema: data.DSchema}
+		if err := runHooks(ctx, config, ddb, "before-schema", v); err != nil {
+			// none of the objects of the schema are copied
+			errs = append(errs, err)
+			continue
+		}
+
+		var steps []func(context.Context, *CopyConfig, *database.Conn) (int, []error, error)
+		if config.Table || config.TableName != "" {
+			logger.Info("tables", "table", s.Name)
+			steps = append(steps, getTables)
+		}
+		if config.View || config.ViewName != "" {
+			logger.Info("views", "view", s.Name)
+			steps = append(steps, getViews)
+		}
+		if config.Routine || config.RoutineName != "" {
+			logger.Info("routines", "routine", s.Name)
+			steps = append(steps, getRoutines)
+		}
+		if config.Index || config.IndexName != "" {
+			logger.Info("indexs", "index", s.Name)
+			steps = append(steps, getIndexes)
+		}
+		for _, step := range steps {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(fmt.Errorf("interrupted: %w", err), failed("objects", total, errs))
+			}
+			n, objErrs, err := step(ctx, config, &data)
+			if err != nil {
+				return err
+			}
+			total += n
+			errs = append(errs, objErrs...)
+		}
+		if err := runHooks(ctx, config, ddb, "after-schema", v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return failed("objects", total, errs)
+}
+
+// lookupSchemas returns the schemas of db, or only name when it is set
+func lookupSchemas(ctx context.Context, db *database.Database, name string) ([]database.Schema, error) {
+	ctx, cancel := timeoutContext(ctx)
+	defer cancel()
+	schemas, err := db.GetSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return schemas, nil
+	}
+	for _, v := range schemas {
+		if v.Name == name {
+			return []database.Schema{{Name: name}}, nil
+		}
+	}
+	return nil, fmt.Errorf("schema %s not found", name)
+}
+
+// getDBConfigs returns the source connection and the destination host
+// names, a group or glob pattern destination copies to each of its hosts
+func (config *CopyConfig) getDBConfigs() (sourceDB database.Database, dests []string, err error) {
+	// =======
+	// source db
+	// =======
+	if config.Source == "" {
+		return sourceDB, nil, errors.New("no source specified")
+	}
+	sourceDB, err = hostDatabase(config.Source)
+	if err != nil {
+		return sourceDB, nil, fmt.Errorf("source: %w", err)
+	}
+
+	// =======
+	// dest DB
+	// =======
+	if config.Dest == "" {
+		return sourceDB, nil, errors.New("no destination specified")
+	}
+	if config.fileDest() {
+		return sourceDB, nil, nil
+	}
+	dests, err = targets(config.Dest)
+	if err != nil {
+		return sourceDB, nil, fmt.Errorf("destination: %w", err)
+	}
+	return sourceDB, dests, nil
+}
+
+// loadMapping builds the rename rules of the --rename file and flags, none
+// when neither is set
+func (config *CopyConfig) loadMapping() error {
+	if config.Rename == "" && config.RenameCase == "" && len(config.RenameSchemas) == 0 &&
+		len(config.RenameTables) == 0 && len(config.RenameColumns) == 0 {
+		return nil
+	}
+	if config.Data {
+		return errors.New("--data cannot rename, the rows are exported with the source names")
+	}
+	m := &database.Mapping{}
+	if config.Rename != "" {
+		var err error
+		if m, err = database.LoadMapping(config.Rename); err != nil {
+			return err
+		}
+	}
+	if config.RenameCase != "" {
+		m.Tables.Case, m.Columns.Case = config.RenameCase, config.RenameCase
+	}
+	for _, r := range []struct {
+		names *map[string]string
+		flag  map[string]string
+	}{
+		{&m.Schemas, config.RenameSchemas},
+		{&m.Tables.Names, config.RenameTables},
+		{&m.Columns.Names, config.RenameColumns},
+	} {
+		if len(r.flag) > 0 && *r.names == nil {
+			*r.names = make(map[string]string)
+		}
+		maps.Copy(*r.names, r.flag)
+	}
+	if err := m.Check(); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	config.mapping = m
+	return nil
+}
+
+// LogValue logs the settings without connection URI passwords
+func (config *CopyConfig) LogValue() slog.Value {
+	c := *config
+	c.Source = hostLabel(c.Source)
+	c.Dest = hostLabel(c.Dest)
+	return slog.AnyValue(c)
+}
+
+func (config *CopyConfig) checkParams() error {
+	// =======
+	// check all or table,view,routine
+	// =======
+
+	if config.All {
+		config.Table = true
+		config.View = true
+		config.Routine = true
+	}
+
+	if (!config.Table && config.TableName == "") &&
+		(!config.View && config.ViewName == "") &&
+		(!config.Routine && config.RoutineName == "") &&
+		(!config.Index && config.IndexName == "") {
+		return errors.New("one of --tables, --views, --routines, --indexes or --all has to be selected")
+	}
+	if config.BackupData && config.Backup == "" {
+		return errors.New("--backup-data needs --backup")
+	}
+	if config.Plan != "" && !config.Debug {
+		return errors.New("--plan needs --dry-run")
+	}
+	if config.Data && !config.fileDest() {
+		return errors.New("--data needs a dir: or archive: destination")
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+// backupPrefix prefix of the schemas --backup-data moves tables to
+const backupPrefix = "dbtools_backup_"
+
+// kindCodes file name code of every object kind
+var kindCodes = map[string]string{"table": "t", "view": "v", "routine": "r", "index": "i"}
+
+// unsafeChars characters not kept in a backup directory name
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// backupDir directory the backups of the run on dest are saved in
+func (config *CopyConfig) backupDir(dest string) string {
+	return filepath.Join(config.Backup, config.backupStamp, unsafeChars.ReplaceAllString(dest, "_"))
+}
+
+// backupObject saves the destination definition of object to a file that
+// restores it, before it is replaced. With --backup-data a table is moved
+// to the backup schema instead of being dropped, move returns the
+// statements that do it in the same transaction as the replacement.
+func backupObject(ctx context.Context, config *CopyConfig, data *database.Conn, kind, object string) (move string, err error) {
+	if config.Backup == "" || config.Debug || config.fileDest() {
+		return "", nil
+	}
+	driver := data.Dest.Driver
+	// the destination is read as the source of its own definitions
+	dconn := database.Conn{Source: data.Dest, Dest: data.Dest, SSchema: data.DSchema, DSchema: data.DSchema, Templates: data.Templates}
+	restore := ""
+	switch kind {
+	case "table":
+		cols, err := dconn.GetColumnDetail(ctx, object)
+		if err != nil || len(cols) == 0 {
+			// nothing to back up when the table does not exist yet
+			return "", err
+		}
+		if config.BackupData {
+			bschema := backupPrefix + config.backupStamp
+			move = database.MoveTableSQL(driver, data.DSchema, bschema, object)
+			restore = database.DropTableSQL(driver, data.DSchema, object) +
+				database.MoveTableSQL(driver, bschema, data.DSchema, object)
+			break
+		}
+		pkey, err := dconn.GetPKey(ctx, object)
+		if err != nil {
+			return "", err
+		}
+		sqld, sqlc, err := dconn.GenTables(object, cols, pkey)
+		if err != nil {
+			return "", err
+		}
+		_, sqlci, err := dconn.GenTableIndexSQL(ctx, object)
+		if err != nil {
+			return "", err
+		}
+		// the views the drop takes with it are restored after the table
+		views, lost, err := dependentViews(ctx, &dconn, object)
+		if err != nil {
+			return "", err
+		}
+		restore = sqld + sqlc + sqlci + restoreDependents(driver, views, lost)
+	case "view":
+		v, err := dconn.GetViewSchema(ctx, data.DSchema, object)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		restore = replaceSQL(driver, "VIEW", data.DSchema, object, viewSQL(driver, data.DSchema, v))
+	case "routine":
+		r, err := dconn.GetRoutineSchema(ctx, data.DSchema, object)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		restore = replaceSQL(driver, r.Type, data.DSchema, object, routineSQL(driver, data.DSchema, r))
+	default:
+		return "", nil
+	}
+
+	dir := config.backupDir(hostLabel(cmp.Or(data.Dest.Name, data.Dest.URI)))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("backup %s: %w", object, err)
+	}
+	fn := filepath.Join(dir, fmt.Sprintf("%s__%s__%s.sql", data.DSchema, kindCodes[kind], object))
+	if err := os.WriteFile(fn, []byte(restore), 0o644); err != nil {
+		return "", fmt.Errorf("backup %s: %w", object, err)
+	}
+	logger.Info("backup", "object", object, "file", fn)
+	return move, nil
+}
+
+// replaceSQL statement recreating a view or routine from its create
+// statement. mssql drops it first and runs the create in its own batch.
+func replaceSQL(driver, otype, schema, name, create string) string {
+	if driver != "mssql" {
+		return create
+	}
+	return fmt.Sprintf("DROP %s IF EXISTS \"%s\".\"%s\";\nEXEC('%s');\n",
+		strings.ToUpper(otype), schema, name, strings.ReplaceAll(create, "'", "''"))
+}
+
+// dependentView definition of a view depending on a backed up table, deps
+// are the objects depending on the view in turn
+type dependentView struct {
+	Schema string
+	View   database.View
+	deps   []database.Dependent
+}
+
+// dependentViews reads the definitions of the views dropping table takes
+// with it, lost are the dependents that cannot be restored from a view
+// definition
+func dependentViews(ctx context.Context, dconn *database.Conn, table string) (views []dependentView, lost []database.Dependent, err error) {
+	dd, err := dconn.Source.GetDependents(ctx, dconn.SSchema, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range dd {
+		if d.Kind != "view" {
+			lost = append(lost, d)
+			continue
+		}
+		v, err := dconn.GetViewSchema(ctx, d.Schema, d.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			lost = append(lost, d)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		vd, err := dconn.Source.GetDependents(ctx, d.Schema, d.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		views = append(views, dependentView{Schema: d.Schema, View: v, deps: vd})
+	}
+	return views, lost, nil
+}
+
+// restoreDependents statements recreating the dependent views of a table.
+// A view is created before the views built on it, whether its dependents
+// are listed transitively or only directly.
+func restoreDependents(driver string, views []dependentView, lost []database.Dependent) string {
+	views = dependencyOrder(views)
+	restore := ""
+	for _, v := range views {
+		restore += "\n" + replaceSQL(driver, "VIEW", v.Schema, v.View.Name, viewSQL(driver, v.Schema, v.View))
+	}
+	for _, d := range lost {
+		restore += fmt.Sprintf("\n-- not restored: %s %s.%s\n", d.Kind, d.Schema, d.Name)
+	}
+	return restore
+}
+
+// dependencyOrder sorts views so each comes before its dependents, views
+// without an order between them keep theirs
+func dependencyOrder(views []dependentView) []dependentView {
+	// before[i] the views view i is built on
+	before := make([][]int, len(views))
+	for i, v := range views {
+		for _, d := range v.deps {
+			for j, w := range views {
+				if j != i && d.Schema == w.Schema && d.Name == w.View.Name {
+					before[j] = append(before[j], i)
+				}
+			}
+		}
+	}
+	sorted := make([]dependentView, 0, len(views))
+	seen := make([]bool, len(views))
+	var visit func(i int)
+	visit = func(i int) {
+		if seen[i] {
+			return
+		}
+		seen[i] = true
+		for _, j := range before[i] {
+			visit(j)
+		}
+		sorted = append(sorted, views[i])
+	}
+	for i := range views {
+		visit(i)
+	}
+	return sorted
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+func TestRestoreDependents(t *testing.T) {
+	views := []dependentView{
+		{Schema: "sales", View: database.View{Name: "big_orders", Definition: " SELECT id FROM sales.open_orders WHERE total > 100;"}},
+		{Schema: "sales", View: database.View{Name: "open_orders", Definition: " SELECT id, total FROM sales.orders WHERE open;"},
+			deps: []database.Dependent{{Schema: "sales", Name: "big_orders", Kind: "view"}}},
+	}
+	lost := []database.Dependent{{Schema: "sales", Name: "lines.lines_order_fkey", Kind: "foreign key"}}
+
+	restore := restoreDependents("pgx", views, lost)
+	open := strings.Index(restore, `CREATE OR REPLACE VIEW "sales"."open_orders" AS`)
+	big := strings.Index(restore, `CREATE OR REPLACE VIEW "sales"."big_orders" AS`)
+	if open < 0 || big < 0 {
+		t.Fatalf("dependent views missing from the backup:\n%s", restore)
+	}
+	if big < open {
+		t.Errorf("big_orders is created before the view it is built on:\n%s", restore)
+	}
+	if !strings.Contains(restore, "-- not restored: foreign key sales.lines.lines_order_fkey") {
+		t.Errorf("lost foreign key not noted:\n%s", restore)
+	}
+
+	restore = restoreDependents("mssql", views[:1], nil)
+	if want := `DROP VIEW IF EXISTS "sales"."big_orders";`; !strings.Contains(restore, want) {
+		t.Errorf("mssql restore missing %s:\n%s", want, restore)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	view := func(name string, deps ...string) dependentView {
+		v := dependentView{Schema: "dbo", View: database.View{Name: name}}
+		for _, d := range deps {
+			v.deps = append(v.deps, database.Dependent{Schema: "dbo", Name: d, Kind: "view"})
+		}
+		return v
+	}
+	names := func(views []dependentView) []string {
+		var nn []string
+		for _, v := range views {
+			nn = append(nn, v.View.Name)
+		}
+		return nn
+	}
+
+	// mssql lists the direct dependents only, postgres all of them
+	for _, c := range []struct {
+		name  string
+		views []dependentView
+	}{
+		{"direct", []dependentView{view("c"), view("b", "c"), view("a", "b")}},
+		{"transitive", []dependentView{view("c"), view("b", "c"), view("a", "b", "c")}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got, want := names(dependencyOrder(c.views)), []string{"a", "b", "c"}; !slices.Equal(got, want) {
+				t.Errorf("dependencyOrder = %v, want %v", got, want)
+			}
+		})
+	}
+}
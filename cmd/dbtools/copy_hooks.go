@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+// hookPoints where hooks run, the hooks are named before-<point> and
+// after-<point>
+var hookPoints = []string{"run", "schema", "table", "link", "update", "view", "routine", "index"}
+
+// hookVars values of the placeholders in the hook sql
+type hookVars struct {
+	dest, sourceSchema, schema, kind, object string
+}
+
+// loadHooks checks the hook names and reads the @<file> scripts, relative
+// to the directory of the settings file
+func (config *CopyConfig) loadHooks() error {
+	config.hooks = make(map[string][]string)
+	for name, scripts := range config.Hooks {
+		point, ok := strings.CutPrefix(name, "before-")
+		if !ok {
+			point, ok = strings.CutPrefix(name, "after-")
+		}
+		if !ok || !slices.Contains(hookPoints, point) {
+			return fmt.Errorf("hooks: unknown hook %s, expected before-<point> or after-<point> with point one of %s",
+				name, strings.Join(hookPoints, ", "))
+		}
+		for _, s := range scripts {
+			if fn, ok := strings.CutPrefix(s, "@"); ok {
+				if !filepath.IsAbs(fn) {
+					fn = filepath.Join(filepath.Dir(global.Config), fn)
+				}
+				data, err := os.ReadFile(fn)
+				if err != nil {
+					return fmt.Errorf("hooks: %s: %w", name, err)
+				}
+				s = string(data)
+			}
+			config.hooks[name] = append(config.hooks[name], s)
+		}
+	}
+	return nil
+}
+
+// hookSQL statements of the hook name on driver with the placeholders
+// replaced, the scripts are split into batches like dbtools apply does
+func (config *CopyConfig) hookSQL(driver, name string, v hookVars) []string {
+	r := strings.NewReplacer(
+		"{dest}", v.dest,
+		"{source_schema}", v.sourceSchema,
+		"{schema}", v.schema,
+		"{kind}", v.kind,
+		"{object}", v.object,
+	)
+	var stmts []string
+	for _, s := range config.hooks[name] {
+		stmts = append(stmts, database.SplitBatches(driver, r.Replace(s))...)
+	}
+	return stmts
+}
+
+// hookPoint hook point of the objects of kind
+func hookPoint(kind string) string {
+	if kind == "foreign table" {
+		return "link"
+	}
+	return kind
+}
+
+// checkSessionHooks rejects SET statements in the run and schema hooks of
+// driver. They run on any connection of the pool, so a setting would apply
+// to whichever objects happen to use that connection later.
+func (config *CopyConfig) checkSessionHooks(driver string) error {
+	for _, name := range slices.Sorted(maps.Keys(config.hooks)) {
+		if !strings.HasSuffix(name, "-run") && !strings.HasSuffix(name, "-schema") {
+			continue
+		}
+		for _, stmt := range config.hookSQL(driver, name, hookVars{}) {
+			if sessionStatement(stmt) {
+				return fmt.Errorf("hooks: %s: %q only sets one pooled connection, use an object hook or the session settings of the host",
+					name, strings.TrimSpace(stmt))
+			}
+		}
+	}
+	return nil
+}
+
+// sessionStatement reports whether the statement, after its comments,
+// starts with SET
+func sessionStatement(stmt string) bool {
+	for line := range strings.Lines(stmt) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		return strings.EqualFold(strings.Fields(line)[0], "SET")
+	}
+	return false
+}
+
+// runHooks runs the run or schema hook name on db one statement at a time,
+// outside of a transaction. File destinations and plans skip them, they
+// only keep the object hooks.
+func runHooks(ctx context.Context, config *CopyConfig, db *database.Database, name string, v hookVars) error {
+	stmts := config.hookSQL(db.Driver, name, v)
+	if len(stmts) == 0 || config.fileDest() || config.plan != nil {
+		return nil
+	}
+	logger.Info("hook", "name", name, "schema", v.schema)
+	ctx, cancel := timeoutContext(ctx)
+	defer cancel()
+	if n, _, err := db.ExecEach(ctx, v.schema, stmts...); err != nil {
+		return fmt.Errorf("%s hook statement %d: %w", name, n+1, err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestCheckSessionHooks(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		hooks map[string][]string
+		ok    bool
+	}{
+		{"object hook", map[string][]string{"before-table": {"SET LOCAL session_replication_role = replica"}}, true},
+		{"update in run hook", map[string][]string{"after-run": {"UPDATE jobs\nSET done = true;"}}, true},
+		{"set in run hook", map[string][]string{"before-run": {"-- replicas\nset session_replication_role = replica;"}}, false},
+		{"set in schema hook", map[string][]string{"after-schema": {"ANALYZE;\nSET search_path = x;"}}, false},
+		{"link hook", map[string][]string{"after-link": {"ANALYZE {schema}.{object}"}}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			config := CopyConfig{Hooks: c.hooks}
+			if err := config.loadHooks(); err != nil {
+				t.Fatal(err)
+			}
+			if err := config.checkSessionHooks("pgx"); (err == nil) != c.ok {
+				t.Errorf("checkSessionHooks() = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+// planEntry planned change of one destination object
+type planEntry struct {
+	Dest       string               `json:"dest"`
+	Schema     string               `json:"schema"`
+	Kind       string               `json:"kind"` // table, link, update, view, routine or index
+	Name       string               `json:"name"`
+	Action     string               `json:"action"` // create, replace or error
+	Rows       int64                `json:"rows"`   // estimated rows of the replaced table
+	Dependents []database.Dependent `json:"dependents,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// copyPlan what a copy run would change on the destinations
+type copyPlan struct {
+	mu sync.Mutex
+
+	Source     string      `json:"source"`
+	Create     int         `json:"create"`
+	Replace    int         `json:"replace"`
+	Dependents int         `json:"dependents"`
+	Rows       int64       `json:"rows"`
+	BackupData bool        `json:"backup_data"` // replaced table rows are kept
+	Objects    []planEntry `json:"objects"`
+}
+
+func newCopyPlan(source string, backupData bool) *copyPlan {
+	return &copyPlan{Source: source, BackupData: backupData, Objects: []planEntry{}}
+}
+
+// add records the plan of an object, safe for concurrent use
+func (p *copyPlan) add(e planEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Objects = append(p.Objects, e)
+}
+
+// planObject compares object with the destination: whether it will be
+// created or replaced, and for a replaced table its estimated rows and the
+// objects dropped with it
+func planObject(ctx context.Context, data *database.Conn, dest, kind, object string) (planEntry, error) {
+	e := planEntry{Dest: dest, Schema: data.DSchema, Kind: kind, Name: object, Action: "create"}
+	switch kind {
+	case "table":
+		e.Name = data.DestTable(object)
+	case "link":
+		e.Name = database.LinkName(data.DestTable(object))
+	case "update":
+		e.Name = database.UpdateName(data.DestTable(object))
+	case "index":
+		// the destination index is named after its table and columns
+		idx, err := data.GetIndexSchema(ctx, data.SSchema, object)
+		if err != nil {
+			return e, err
+		}
+		name, _, _ := data.GenIndex(idx)
+		e.Name = strings.Trim(name, `"`)
+	}
+	exists, err := data.Dest.ObjectExists(ctx, data.DSchema, e.Name)
+	if err != nil || !exists {
+		return e, err
+	}
+	e.Action = "replace"
+	if kind != "table" {
+		return e, nil
+	}
+	if e.Rows, err = data.Dest.GetRowEstimate(ctx, data.DSchema, e.Name); err != nil {
+		return e, err
+	}
+	e.Dependents, err = data.Dest.GetDependents(ctx, data.DSchema, e.Name)
+	return e, err
+}
+
+// planKinds the kinds of destination objects copying a source object of
+// kind creates, as copyObject runs them
+func planKinds(config *CopyConfig, driver, kind string) []string {
+	if kind != "table" {
+		return []string{kind}
+	}
+	var kinds []string
+	if config.Table {
+		kinds = append(kinds, "table")
+	}
+	if config.Link && (driver == "postgres" || driver == "pgx") {
+		kinds = append(kinds, "link")
+	}
+	if config.Update {
+		kinds = append(kinds, "update")
+	}
+	return kinds
+}
+
+// finish totals the plan and sorts it by destination and object
+func (p *copyPlan) finish() {
+	p.Create, p.Replace, p.Dependents, p.Rows = 0, 0, 0, 0
+	for _, e := range p.Objects {
+		switch e.Action {
+		case "create":
+			p.Create++
+		case "replace":
+			p.Replace++
+			p.Dependents += len(e.Dependents)
+			p.Rows += e.Rows
+		}
+	}
+	slices.SortStableFunc(p.Objects, func(a, b planEntry) int {
+		return cmp.Or(strings.Compare(a.Dest, b.Dest), strings.Compare(a.Schema, b.Schema),
+			strings.Compare(a.Kind, b.Kind), strings.Compare(a.Name, b.Name))
+	})
+}
+
+// print writes the plan as a table, the destination column only when there
+// are several
+func (p *copyPlan) print(out io.Writer) {
+	if len(p.Objects) == 0 {
+		fmt.Fprintln(out, "No changes.")
+		return
+	}
+	dests := slices.ContainsFunc(p.Objects, func(e planEntry) bool { return e.Dest != p.Objects[0].Dest })
+	header := []string{"ACTION", "KIND", "NAME", "ROWS", "DEPENDENTS"}
+	if dests {
+		header = append([]string{"DEST"}, header...)
+	}
+	lines := [][]string{header}
+	for _, e := range p.Objects {
+		rows := ""
+		if e.Kind == "table" && e.Action == "replace" {
+			rows = "~" + strconv.FormatInt(e.Rows, 10)
+		}
+		var deps []string
+		for _, d := range e.Dependents {
+			deps = append(deps, d.Kind+" "+d.Schema+"."+d.Name)
+		}
+		if e.Error != "" {
+			deps = []string{e.Error}
+		}
+		line := []string{e.Action, e.Kind, e.Schema + "." + e.Name, rows, strings.Join(deps, ", ")}
+		if dests {
+			line = append([]string{e.Dest}, line...)
+		}
+		lines = append(lines, line)
+	}
+	action := slices.Index(header, "ACTION")
+	fmt.Fprintln(out)
+	printColumns(out, lines, func(row, col int, v string) string {
+		switch {
+		case row == 0 || col != action:
+			return v
+		case strings.TrimSpace(v) == "create":
+			return okStyle.Render(v)
+		case strings.TrimSpace(v) == "replace":
+			return canceledStyle.Render(v)
+		default:
+			return failStyle.Render(v)
+		}
+	})
+	rows := "dropped"
+	if p.BackupData {
+		rows = "moved to the backup schema"
+	}
+	fmt.Fprintf(out, "Plan: %d to create, %d to replace, %d dependents affected, ~%d rows %s\n",
+		p.Create, p.Replace, p.Dependents, p.Rows, rows)
+}
+
+// write saves the plan as JSON to fn, - is stdout
+func (p *copyPlan) write(out io.Writer, fn string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("plan: %w", err)
+	}
+	data = append(data, '\n')
+	if fn == "-" {
+		_, err = out.Write(data)
+		return err
+	}
+	if err := os.WriteFile(fn, data, 0o644); err != nil {
+		return fmt.Errorf("plan: %w", err)
+	}
+	return nil
+}
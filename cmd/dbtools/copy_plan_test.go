@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanKinds(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config CopyConfig
+		driver string
+		kind   string
+		want   []string
+	}{
+		{"table", CopyConfig{Table: true}, "postgres", "table", []string{"table"}},
+		{"all", CopyConfig{Table: true, Link: true, Update: true}, "postgres", "table", []string{"table", "link", "update"}},
+		{"link on pgx", CopyConfig{Table: true, Link: true}, "pgx", "table", []string{"table", "link"}},
+		{"link on mssql is skipped", CopyConfig{Link: true, Update: true}, "mssql", "table", []string{"update"}},
+		{"view", CopyConfig{View: true}, "postgres", "view", []string{"view"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := planKinds(&c.config, c.driver, c.kind); !slices.Equal(got, c.want) {
+				t.Errorf("planKinds = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
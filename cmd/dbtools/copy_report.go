@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// objectResult outcome of copying one object
+type objectResult struct {
+	Dest       string  `json:"dest"`
+	Schema     string  `json:"schema"`
+	Kind       string  `json:"kind"` // table, view, routine or index
+	Name       string  `json:"name"`
+	Status     string  `json:"status"` // ok, failed or canceled
+	Seconds    float64 `json:"seconds"`
+	Statements int     `json:"statements"`
+	Rows       int64   `json:"rows"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// copyReport results of a copy run
+type copyReport struct {
+	mu sync.Mutex
+
+	Source   string         `json:"source"`
+	Started  time.Time      `json:"started"`
+	Seconds  float64        `json:"seconds"`
+	Objects  int            `json:"objects"`
+	Failed   int            `json:"failed"`
+	Canceled int            `json:"canceled"`
+	Results  []objectResult `json:"results"`
+}
+
+func newCopyReport(source string) *copyReport {
+	return &copyReport{Source: source, Started: time.Now(), Results: []objectResult{}}
+}
+
+// add records the result of an object, safe for concurrent use
+func (r *copyReport) add(res objectResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Results = append(r.Results, res)
+}
+
+// finish totals the results and sorts them by destination and object
+func (r *copyReport) finish() {
+	r.Seconds = time.Since(r.Started).Seconds()
+	r.Objects = len(r.Results)
+	r.Failed, r.Canceled = 0, 0
+	for _, res := range r.Results {
+		switch res.Status {
+		case "failed":
+			r.Failed++
+		case "canceled":
+			r.Canceled++
+		}
+	}
+	slices.SortStableFunc(r.Results, func(a, b objectResult) int {
+		return cmp.Or(strings.Compare(a.Dest, b.Dest), strings.Compare(a.Schema, b.Schema),
+			strings.Compare(a.Kind, b.Kind), strings.Compare(a.Name, b.Name))
+	})
+}
+
+// print writes the summary table, the destination column only when there
+// are several
+func (r *copyReport) print(out io.Writer) {
+	if len(r.Results) == 0 {
+		return
+	}
+	dests := slices.ContainsFunc(r.Results, func(res objectResult) bool { return res.Dest != r.Results[0].Dest })
+	header := []string{"SCHEMA", "KIND", "NAME", "STATUS", "STMTS", "ROWS", "TIME", "ERROR"}
+	if dests {
+		header = append([]string{"DEST"}, header...)
+	}
+	lines := [][]string{header}
+	for _, res := range r.Results {
+		line := []string{res.Schema, res.Kind, res.Name, res.Status, strconv.Itoa(res.Statements),
+			strconv.FormatInt(res.Rows, 10), seconds(res.Seconds), res.Error}
+		if dests {
+			line = append([]string{res.Dest}, line...)
+		}
+		lines = append(lines, line)
+	}
+	status := slices.Index(header, "STATUS")
+	fmt.Fprintln(out)
+	printColumns(out, lines, func(row, col int, v string) string {
+		switch {
+		case row == 0 || col != status:
+			return v
+		case strings.TrimSpace(v) == "ok":
+			return okStyle.Render(v)
+		case strings.TrimSpace(v) == "canceled":
+			return canceledStyle.Render(v)
+		default:
+			return failStyle.Render(v)
+		}
+	})
+	summary := fmt.Sprintf("%d objects, %d failed", r.Objects, r.Failed)
+	if r.Canceled > 0 {
+		summary += fmt.Sprintf(", %d canceled", r.Canceled)
+	}
+	fmt.Fprintf(out, "%s in %s\n", summary, seconds(r.Seconds))
+}
+
+// seconds formats a duration in seconds
+func seconds(s float64) string {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+// write saves the report to fn, JUnit XML for a .xml file and JSON otherwise
+func (r *copyReport) write(fn string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(fn), ".xml") {
+		data, err = r.junit()
+	} else {
+		data, err = json.MarshalIndent(r, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	if err := os.WriteFile(fn, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	return nil
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+
+	seconds float64
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit the report as JUnit XML, a test suite per destination and a test
+// case per object
+func (r *copyReport) junit() ([]byte, error) {
+	suites := junitSuites{}
+	for _, res := range r.Results {
+		i := slices.IndexFunc(suites.Suites, func(s junitSuite) bool { return s.Name == res.Dest })
+		if i < 0 {
+			suites.Suites = append(suites.Suites, junitSuite{Name: res.Dest})
+			i = len(suites.Suites) - 1
+		}
+		s := &suites.Suites[i]
+		c := junitCase{
+			Name:      res.Kind + " " + res.Name,
+			Classname: res.Schema,
+			Time:      strconv.FormatFloat(res.Seconds, 'f', 3, 64),
+		}
+		if res.Status != "ok" {
+			c.Failure = &junitFailure{Message: res.Error, Text: res.Error}
+			s.Failures++
+		}
+		s.Tests++
+		s.Cases = append(s.Cases, c)
+		s.seconds += res.Seconds
+		s.Time = strconv.FormatFloat(s.seconds, 'f', 3, 64)
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ppreeper/dbtools/pkg/manifest"
+)
+
+// sink receives the sql of every object instead of a destination database
+type sink interface {
+	// add keeps the sql of an object, safe for concurrent use
+	add(o manifest.Object)
+	// dataFile returns a new file for the rows of a table, removed on close
+	dataFile() (*os.File, error)
+	// close writes the output once every object has been added
+	close() error
+	// discard drops the output of a failed run
+	discard()
+}
+
+// fileDest reports whether the sql is written to files instead of run on a
+// destination database
+func (config *CopyConfig) fileDest() bool {
+	return config.Dest == "file:" || strings.HasPrefix(config.Dest, "dir:") || strings.HasPrefix(config.Dest, "archive:")
+}
+
+// newSink returns the sink of a file destination, file: is short for dir:.
+func (config *CopyConfig) newSink(driver string) (sink, error) {
+	s := &dirSink{source: hostLabel(config.Source), driver: driver}
+	var ok bool
+	switch {
+	case config.Dest == "file:":
+		s.dir, ok = ".", true
+	case strings.HasPrefix(config.Dest, "archive:"):
+		s.archive, ok = strings.CutPrefix(config.Dest, "archive:")
+		if !manifest.IsArchive(s.archive) {
+			return nil, fmt.Errorf("destination %s: expected archive:<file>.tar.gz, .tgz or .zip", config.Dest)
+		}
+	default:
+		s.dir, ok = strings.CutPrefix(config.Dest, "dir:")
+	}
+	if !ok || s.dir == "" && s.archive == "" {
+		return nil, fmt.Errorf("destination %s: expected dir:<path>", config.Dest)
+	}
+	return s, nil
+}
+
+// dirSink writes a tree of sql files per schema and kind with a manifest,
+// or packs the tree into an archive
+type dirSink struct {
+	dir     string
+	archive string
+	source  string
+	driver  string
+
+	mu   sync.Mutex
+	objs []manifest.Object
+	tmp  string
+}
+
+func (s *dirSink) add(o manifest.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objs = append(s.objs, o)
+}
+
+func (s *dirSink) dataFile() (*os.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tmp == "" {
+		tmp, err := os.MkdirTemp("", "dbtools-data-")
+		if err != nil {
+			return nil, fmt.Errorf("data file: %w", err)
+		}
+		s.tmp = tmp
+	}
+	f, err := os.CreateTemp(s.tmp, "*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("data file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *dirSink) close() error {
+	defer s.discard()
+	m, err := manifest.Build(s.source, s.driver, s.objs)
+	if err != nil {
+		return err
+	}
+	if s.archive != "" {
+		return m.WriteArchive(s.archive)
+	}
+	return m.WriteDir(s.dir)
+}
+
+func (s *dirSink) discard() {
+	if s.tmp != "" {
+		os.RemoveAll(s.tmp)
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/manifest"
+)
+
+// getTables copies the tables of the schema, returning the number of objects
+// and the errors of the failed ones, listing them has to succeed
+func getTables(ctx context.Context, config *CopyConfig, data *database.Conn) (int, []error, error) {
+	var err error
+	var errs []error
+	var sTables []database.Table
+
+	if config.TableName != "" {
+		sTables = []database.Table{{Name: config.TableName}}
+	} else {
+		lctx, cancel := timeoutContext(ctx)
+		sTables, err = data.GetTables(lctx, data.SSchema, "BASE TABLE")
+		cancel()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var tbls []string
+	for _, t := range sTables {
+		if config.FilterDef == "" || !config.Filter.MatchString(t.Name) {
+			tbls = append(tbls, t.Name)
+		}
+	}
+	logger.Info("sTables", "count", len(sTables), "tables", len(tbls))
+	if err := data.Mapping.CheckTables(tbls); err != nil {
+		return 0, nil, fmt.Errorf("rename: %w", err)
+	}
+
+	cTable := config.Table
+	cLink := config.Link
+	cView := config.View
+	cRoutine := config.Routine
+
+	// config.Table = true
+	config.View = false
+	// config.Link = false
+	// config.Routine = false
+
+	if len(tbls) > 0 {
+		logger.Info("tables", "tables", tbls)
+		errs = backupTasker(ctx, config, data, "table", tbls)
+	}
+
+	config.Table = cTable
+	config.Link = cLink
+	config.View = cView
+	config.Routine = cRoutine
+	return len(tbls), errs, nil
+}
+
+// getViews copies the views of the schema, returning the number of objects
+// and the errors of the failed ones, listing them has to succeed
+func getViews(ctx context.Context, config *CopyConfig, data *database.Conn) (int, []error, error) {
+	var err error
+	var errs []error
+	var sViews []database.ViewList
+
+	if config.ViewName != "" {
+		sViews = []database.ViewList{{Name: config.ViewName}}
+	} else {
+		lctx, cancel := timeoutContext(ctx)
+		sViews, err = data.GetViews(lctx, data.SSchema)
+		cancel()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var views []string
+	for _, t := range sViews {
+		if config.FilterDef == "" || !config.Filter.MatchString(t.Name) {
+			views = append(views, t.Name)
+		}
+	}
+	logger.Info("sViews", "count", len(sViews), "tables", len(views))
+
+	cTable := config.Table
+	cLink := config.Link
+	cView := config.View
+	cRoutine := config.Routine
+
+	config.Table = false
+	config.View = true
+	config.Link = false
+	config.Routine = false
+
+	if len(views) > 0 {
+		logger.Info("views", "views", views)
+		errs = backupTasker(ctx, config, data, "view", views)
+	}
+
+	config.Table = cTable
+	config.Link = cLink
+	config.View = cView
+	config.Routine = cRoutine
+	return len(views), errs, nil
+}
+
+// getRoutines copies the routines of the schema, returning the number of objects
+// and the errors of the failed ones, listing them has to succeed
+func getRoutines(ctx context.Context, config *CopyConfig, data *database.Conn) (int, []error, error) {
+	var err error
+	var errs []error
+	var sRoutines []database.RoutineList
+
+	if config.RoutineName != "" {
+		sRoutines = []database.RoutineList{{Name: config.RoutineName}}
+	} else {
+		lctx, cancel := timeoutContext(ctx)
+		sRoutines, err = data.GetRoutines(lctx, data.SSchema)
+		cancel()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var routines []string
+	for _, t := range sRoutines {
+		if config.FilterDef == "" || !config.Filter.MatchString(t.Name) {
+			routines = append(routines, t.Name)
+		}
+	}
+	logger.Info("sRoutines", "count", len(sRoutines), "tables", len(routines))
+
+	cTable := config.Table
+	cLink := config.Link
+	cView := config.View
+	cRoutine := config.Routine
+
+	config.Table = false
+	config.View = false
+	config.Link = false
+	config.Routine = true
+
+	if len(routines) > 0 {
+		logger.Info("routines", "routines", routines)
+		errs = backupTasker(ctx, config, data, "routine", routines)
+	}
+
+	config.Table = cTable
+	config.Link = cLink
+	config.View = cView
+	config.Routine = cRoutine
+	return len(routines), errs, nil
+}
+
+// getIndexes copies the indexes of the schema, returning the number of objects
+// and the errors of the failed ones, listing them has to succeed
+func getIndexes(ctx context.Context, config *CopyConfig, data *database.Conn) (int, []error, error) {
+	var err error
+	var errs []error
+	var sIndexes []database.IndexList
+
+	if config.IndexName != "" {
+		sIndexes = []database.IndexList{{Name: config.IndexName}}
+	} else {
+		lctx, cancel := timeoutContext(ctx)
+		sIndexes, err = data.GetIndexes(lctx, data.SSchema)
+		cancel()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var indexes []string
+	for _, t := range sIndexes {
+		if config.FilterDef == "" || !config.Filter.MatchString(t.Name) {
+			indexes = append(indexes, t.Name)
+		}
+	}
+	logger.Info("sIndexes", "count", len(sIndexes), "tables", len(indexes))
+
+	if len(indexes) > 0 {
+		logger.Info("indexes", "indexes", indexes)
+		errs = backupTasker(ctx, config, data, "index", indexes)
+	}
+	return len(indexes), errs, nil
+}
+
+// backupTasker copies the objects concurrently, a failed statement stops
+// its object and the others carry on. Once ctx is canceled the objects not
+// started yet are skipped. Every object is added to the report.
+func backupTasker(ctx context.Context, config *CopyConfig, data *database.Conn, kind string, objects []string) []error {
+	dest := destLabel(config, data)
+	sem := make(chan int, config.JobCount)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	wg.Add(len(objects))
+	for _, object := range objects {
+		go func() {
+			defer wg.Done()
+			sem <- 1
+			defer func() { <-sem }()
+
+			if config.plan != nil {
+				for _, k := range planKinds(config, data.Dest.Driver, kind) {
+					octx, cancel := timeoutContext(ctx)
+					e, err := planObject(octx, data, dest, k, object)
+					cancel()
+					if err != nil {
+						logger.Error("plan", "object", object, "error", err)
+						e.Action, e.Error = "error", err.Error()
+						mu.Lock()
+						errs = append(errs, err)
+						mu.Unlock()
+					}
+					config.plan.add(e)
+				}
+				return
+			}
+
+			res := objectResult{Dest: dest, Schema: data.DSchema, Kind: kind, Name: object, Status: "ok"}
+			err := ctx.Err()
+			if err == nil {
+				start := time.Now()
+				res.Statements, res.Rows, err = copyObject(ctx, config, data, object)
+				res.Seconds = time.Since(start).Seconds()
+			}
+			if err != nil {
+				logger.Error("copy", "object", object, "error", err)
+				res.Status, res.Error = "failed", err.Error()
+				if errors.Is(err, context.Canceled) {
+					res.Status = "canceled"
+				}
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+			config.report.add(res)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// destLabel name of the destination of data in the report
+func destLabel(config *CopyConfig, data *database.Conn) string {
+	if config.fileDest() {
+		return config.Dest
+	}
+	return hostLabel(cmp.Or(data.Dest.Name, data.Dest.URI))
+}
+
+// copyObject generates the sql of the selected object kinds and prints it,
+// writes it to a file or executes it on the destination. It returns the
+// number of statements and the rows they affected. The statements are
+// bounded by the global timeout, exporting the rows is not.
+func copyObject(ctx context.Context, config *CopyConfig, data *database.Conn, object string) (stmts int, rows int64, err error) {
+	rctx := ctx
+	ctx, cancel := timeoutContext(ctx)
+	defer cancel()
+	var deps []string
+	run := func(kind string, sql ...string) error {
+		o := manifest.Object{Schema: data.DSchema, Kind: kind, Name: object, Dependents: deps}
+		// the object hooks run in the same transaction
+		v := hookVars{dest: destLabel(config, data), sourceSchema: data.SSchema, schema: data.DSchema, kind: kind, object: object}
+		sql = append(config.hookSQL(data.Dest.Driver, "before-"+hookPoint(kind), v), sql...)
+		sql = append(sql, config.hookSQL(data.Dest.Driver, "after-"+hookPoint(kind), v)...)
+		n, r, err := output(ctx, config, data, o, sql...)
+		stmts += n
+		rows += r
+		return err
+	}
+	if config.Table {
+		stage, swap, err := data.GetTableSchema(ctx, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		logger.Info("sql", "stage", stage, "swap", swap)
+		move, err := backupObject(ctx, config, data, "table", data.DestTable(object))
+		if err != nil {
+			return stmts, rows, err
+		}
+		// one transaction, readers wait for the swap and never miss the table
+		if err := run("table", stage, move, swap); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.Link && (data.Dest.Driver == "postgres" || data.Dest.Driver == "pgx") {
+		dsql, csql, err := data.GetForeignTableSchema(ctx, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		if err := run("foreign table", dsql, csql); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.Update {
+		dsql, csql, err := data.GetUpdateTableSchema(ctx, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		if err := run("update", dsql, csql); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.View {
+		vsql, err := data.GetViewSchema(ctx, data.SSchema, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		if _, err := backupObject(ctx, config, data, "view", object); err != nil {
+			return stmts, rows, err
+		}
+		if config.sink != nil {
+			// the tree orders views after the views they depend on
+			if deps, err = viewDependents(ctx, data, object); err != nil {
+				return stmts, rows, err
+			}
+		}
+		csql := viewSQL(data.Dest.Driver, data.DSchema, vsql)
+		if err := run("view", csql); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.Routine {
+		rsql, err := data.GetRoutineSchema(ctx, data.SSchema, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		if _, err := backupObject(ctx, config, data, "routine", object); err != nil {
+			return stmts, rows, err
+		}
+		csql := routineSQL(data.Dest.Driver, data.DSchema, rsql)
+		if err := run("routine", csql); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.Index {
+		rsql, err := data.GetIndexSchema(ctx, data.SSchema, object)
+		if err != nil {
+			return stmts, rows, err
+		}
+		_, dsql, csql := data.GenIndex(rsql)
+		if err := run("index", dsql, csql); err != nil {
+			return stmts, rows, err
+		}
+	}
+
+	if config.Table && config.Data && config.sink != nil {
+		n, err := exportRows(rctx, config, data, object)
+		rows += n
+		if err != nil {
+			return stmts, rows, err
+		}
+	}
+	return stmts, rows, nil
+}
+
+// viewSQL create statement of view in schema
+func viewSQL(driver, schema string, v database.View) string {
+	csql := ""
+	if driver == "postgres" || driver == "pgx" {
+		csql += fmt.Sprintf("CREATE OR REPLACE VIEW \"%s\".\"%s\" AS\n", schema, v.Name)
+	}
+	return csql + v.Definition
+}
+
+// routineSQL create statement of routine in schema
+func routineSQL(driver, schema string, r database.Routine) string {
+	pg := driver == "postgres" || driver == "pgx"
+	csql := ""
+	if pg {
+		csql = fmt.Sprintf("CREATE OR REPLACE %s \"%s\".\"%s\"", r.Type, schema, r.Name)
+		csql += fmt.Sprintf("() \nLANGUAGE %s\nAS $%s$", r.ExternalLanguage, strings.ToLower(r.Type))
+	}
+	csql += r.Definition
+	if pg {
+		csql += fmt.Sprintf("$%s$\n;", strings.ToLower(r.Type))
+	}
+	return csql
+}
+
+// output prints the statements of object o with --dry-run, adds them to the
+// sink of a file destination or executes them in order on the destination
+func output(ctx context.Context, config *CopyConfig, data *database.Conn, o manifest.Object, stmts ...string) (int, int64, error) {
+	var sql []string
+	for _, q := range stmts {
+		if q = strings.TrimSpace(q); q != "" {
+			sql = append(sql, q)
+		}
+	}
+	switch {
+	case config.Debug:
+		for _, q := range stmts {
+			fmt.Println(q)
+		}
+	case config.sink != nil:
+		// a file is split back into batches when it is applied
+		o.SQL = database.JoinBatches(data.Dest.Driver, sql)
+		config.sink.add(o)
+	default:
+		return data.Dest.ExecDDL(ctx, o.Schema+"."+o.Name, stmts...)
+	}
+	return len(sql), 0, nil
+}
+
+// exportRows adds the rows of the source table to the sink of a file
+// destination
+func exportRows(ctx context.Context, config *CopyConfig, data *database.Conn, table string) (int64, error) {
+	f, err := config.sink.dataFile()
+	if err != nil {
+		return 0, err
+	}
+	n, err := data.Source.ExportTable(ctx, data.SSchema, table, f)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("export rows %s: %w", table, cerr)
+	}
+	if err != nil {
+		return n, err
+	}
+	config.sink.add(manifest.Object{Schema: data.DSchema, Kind: "data", Name: table, File: f.Name()})
+	return n, nil
+}
+
+// viewDependents returns the views of the schema that depend on view
+func viewDependents(ctx context.Context, data *database.Conn, view string) ([]string, error) {
+	dd, err := data.Source.GetDependents(ctx, data.SSchema, view)
+	if err != nil {
+		return nil, err
+	}
+	var deps []string
+	for _, d := range dd {
+		if d.Schema == data.SSchema && strings.HasSuffix(d.Kind, "view") {
+			deps = append(deps, d.Name)
+		}
+	}
+	return deps, nil
+}
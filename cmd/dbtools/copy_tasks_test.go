@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/manifest"
+)
+
+// memSink keeps the objects added to it
+type memSink struct {
+	mu   sync.Mutex
+	objs []manifest.Object
+}
+
+func (s *memSink) add(o manifest.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objs = append(s.objs, o)
+}
+
+func (s *memSink) dataFile() (*os.File, error) { return os.CreateTemp("", "*.jsonl") }
+func (s *memSink) close() error                { return nil }
+func (s *memSink) discard()                    {}
+
+// TestSinkBatches checks that the file of generated link and update sql is
+// split back into the statements that were generated
+func TestSinkBatches(t *testing.T) {
+	cols := []database.Column{{ColumnName: "id", DataType: "INT", IsNullable: "NOT NULL"}, {ColumnName: "name", DataType: "VARCHAR(50)"}}
+	pkey := []database.PKey{{PKey: "id"}}
+	for _, driver := range []string{"mssql", "pgx"} {
+		db := &database.Database{Name: "erp", Hostname: "srv", Database: "erp", Driver: driver}
+		data := &database.Conn{Source: db, Dest: db, SSchema: "dbo", DSchema: "dbo"}
+		s := &memSink{}
+		config := &CopyConfig{sink: s}
+
+		gens := map[string]func(string, []database.Column, []database.PKey) (string, string, error){
+			"link":   data.GenLink,
+			"update": data.GenUpdate,
+		}
+		for kind, gen := range gens {
+			dsql, csql, err := gen("orders", cols, pkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := output(context.Background(), config, data, manifest.Object{Schema: "dbo", Kind: kind, Name: "orders"}, dsql, csql); err != nil {
+				t.Fatal(err)
+			}
+			o := s.objs[len(s.objs)-1]
+			want := []string{strings.TrimSpace(dsql), strings.TrimSpace(csql)}
+			got := database.SplitBatches(driver, o.SQL)
+			if driver != "mssql" {
+				// the postgres statements are split at every semicolon
+				got = []string{strings.Join(got, "\n")}
+				want = []string{strings.Join(want, "\n")}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("%s %s batches:\n got %q\nwant %q", driver, kind, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/migrate"
+	"github.com/ppreeper/dbtools/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// DiffConfig diff command settings
+type DiffConfig struct {
+	Source string `mapstructure:"source"`
+	Dest   string `mapstructure:"dest"`
+	Schema string `mapstructure:"schema"`
+	Format string `mapstructure:"format"`
+	Emit   string `mapstructure:"emit-migration"`
+	Name   string `mapstructure:"name"`
+}
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compare the schemas of two databases or snapshots",
+		Long: `compare the schemas of two databases or snapshots
+
+Prints the changes that make the destination match the source, one per line
+marked + (add), - (drop) or ~ (alter). --source and --dest take a host name
+or a snapshot .json file.
+
+--emit-migration also writes the changes as the next migration of a
+dbtools migrate directory: an up script that makes the destination match
+the source and a down script that reverts it, in the destination dialect.
+What a snapshot cannot generate, like a postgres routine signature, is left
+as a comment to finish by hand.`,
+		Example: `  dbtools diff -s prod -d dev --schema public
+  dbtools diff -s prod.json -d dev
+  dbtools diff -s prod -d dev --emit-migration migrations --name add_orders`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := DiffConfig{}
+			if err := loadConfig(cmd, "diff", &config); err != nil {
+				return err
+			}
+			if config.Source == "" || config.Dest == "" {
+				return errors.New("--source and --dest have to be specified")
+			}
+			src, err := loadSnapshot(cmd.Context(), config.Source, config.Schema)
+			if err != nil {
+				return err
+			}
+			dst, err := loadSnapshot(cmd.Context(), config.Dest, config.Schema)
+			if err != nil {
+				return err
+			}
+
+			cc := snapshot.Diff(dst, src)
+			if config.Format == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(cc); err != nil {
+					return err
+				}
+				if config.Emit != "" {
+					return emitMigration(io.Discard, &config, src, dst, cc)
+				}
+				return nil
+			}
+			snapshot.Print(cmd.OutOrStdout(), cc)
+			if config.Emit != "" {
+				return emitMigration(cmd.OutOrStdout(), &config, src, dst, cc)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringP("source", "s", "", "source host, connection URI or snapshot file")
+	cmd.Flags().StringP("dest", "d", "", "destination host, connection URI or snapshot file")
+	cmd.Flags().String("schema", "", "only this schema")
+	cmd.Flags().StringP("format", "o", "text", "output format: text, json")
+	cmd.Flags().String("emit-migration", "", "write the changes as the next migration in this directory")
+	cmd.Flags().String("name", "diff", "name of the emitted migration")
+	return cmd
+}
+
+// emitMigration writes the changes cc turning dst into src as the next
+// migration of the --emit-migration directory
+func emitMigration(out io.Writer, config *DiffConfig, src, dst *snapshot.Snapshot, cc []snapshot.Change) error {
+	// types and defaults are copied as they are, they only fit one dialect
+	if src.Driver != "" && dst.Driver != "" && dialect(src.Driver) != dialect(dst.Driver) {
+		return fmt.Errorf("cannot emit a migration from %s to %s, the source and destination dialects differ", src.Driver, dst.Driver)
+	}
+	if len(cc) == 0 {
+		fmt.Fprintln(out, "no changes, no migration written")
+		return nil
+	}
+	driver := cmp.Or(dst.Driver, src.Driver)
+	header := fmt.Sprintf("-- dbtools diff -s %s -d %s\n", hostLabel(config.Source), hostLabel(config.Dest))
+	up := header + snapshot.MigrationSQL(driver, dst, src, cc)
+	down := header + snapshot.MigrationSQL(driver, src, dst, snapshot.Diff(src, dst))
+	fn, err := migrate.Write(config.Emit, config.Name, up, down)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "wrote", fn)
+	return nil
+}
+
+// loadSnapshot reads a snapshot file, or takes one from the host name
+func loadSnapshot(ctx context.Context, name, schema string) (*snapshot.Snapshot, error) {
+	if strings.HasSuffix(name, ".json") {
+		snap, err := snapshot.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		if schema != "" {
+			snap.Schemas = filterSchema(snap.Schemas, schema)
+		}
+		return snap, nil
+	}
+	db, err := openHost(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return snapshot.Take(ctx, db, hostLabel(name), schema, global.Timeout)
+}
+
+func filterSchema(schemas []snapshot.Schema, name string) []snapshot.Schema {
+	for _, s := range schemas {
+		if s.Name == name {
+			return []snapshot.Schema{s}
+		}
+	}
+	return nil
+}
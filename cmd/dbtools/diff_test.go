@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppreeper/dbtools/pkg/snapshot"
+)
+
+func TestEmitMigrationDialects(t *testing.T) {
+	dir := t.TempDir()
+	config := &DiffConfig{Emit: dir, Name: "diff"}
+	src := &snapshot.Snapshot{Driver: "mssql", Schemas: []snapshot.Schema{{Name: "dbo"}}}
+	dst := &snapshot.Snapshot{Driver: "pgx"}
+	if err := emitMigration(io.Discard, config, src, dst, snapshot.Diff(dst, src)); err == nil {
+		t.Error("mssql to pgx migration: expected error")
+	}
+
+	src.Driver = "postgres"
+	if err := emitMigration(io.Discard, config, src, dst, snapshot.Diff(dst, src)); err != nil {
+		t.Fatal(err)
+	}
+	if fns, _ := filepath.Glob(filepath.Join(dir, "*")); len(fns) == 0 {
+		t.Errorf("postgres to pgx migration not written")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ppreeper/dbtools/pkg/configfile"
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+// Exit codes, the first matching error kind wins
+const (
+	exitOK          = 0
+	exitError       = 1   // usage and other errors
+	exitConfig      = 2   // host config file problems
+	exitConnection  = 3   // a database could not be opened
+	exitCatalog     = 4   // a catalog query failed
+	exitDDL         = 5   // a ddl statement failed
+	exitUnsupported = 6   // the driver does not support the operation
+	exitInterrupted = 130 // interrupted with Ctrl-C
+)
+
+// exitCodes help text of the exit codes
+const exitCodes = `Exit codes:
+    0  success
+    1  usage and other errors
+    2  host config file problems
+    3  a database could not be opened
+    4  a catalog query failed
+    5  a ddl statement failed
+    6  the driver does not support the operation
+  130  interrupted with Ctrl-C`
+
+// exitCode maps err to its exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, configfile.ErrConfig):
+		return exitConfig
+	case errors.Is(err, database.ErrConnection):
+		return exitConnection
+	case errors.Is(err, database.ErrCatalog):
+		return exitCatalog
+	case errors.Is(err, database.ErrDDL):
+		return exitDDL
+	case errors.Is(err, database.ErrUnsupported):
+		return exitUnsupported
+	}
+	return exitError
+}
+
+// failedError some of several items failed, their errors have already been
+// reported and are kept for the exit code
+type failedError struct {
+	what  string
+	total int
+	errs  []error
+}
+
+func (e *failedError) Error() string {
+	return fmt.Sprintf("%d of %d %s failed", len(e.errs), e.total, e.what)
+}
+
+func (e *failedError) Unwrap() []error { return e.errs }
+
+// failed returns a failedError, nil when errs is empty
+func failed(what string, total int, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &failedError{what: what, total: total, errs: errs}
+}
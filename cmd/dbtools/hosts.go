@@ -0,0 +1,318 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/ppreeper/dbtools/pkg/configfile"
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/str"
+	"github.com/spf13/cobra"
+)
+
+var (
+	okStyle       = lipgloss.NewStyle().Foreground(lipgloss.Green)
+	failStyle     = lipgloss.NewStyle().Foreground(lipgloss.Red)
+	canceledStyle = lipgloss.NewStyle().Foreground(lipgloss.Yellow)
+)
+
+func newHostsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hosts",
+		Short: "list the hosts in the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			conf, err := loadHosts()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			lines := [][]string{{"NAME", "DRIVER", "HOSTNAME", "PORT", "DATABASE", "USERNAME"}}
+			for _, name := range conf.Names() {
+				h := conf.Hosts[name]
+				lines = append(lines, []string{name, h.Driver, h.Hostname, strconv.Itoa(h.Port), h.Database, h.Username})
+			}
+			printColumns(out, lines, nil)
+
+			if len(conf.Groups) == 0 {
+				return nil
+			}
+			groups := make([]string, 0, len(conf.Groups))
+			for g := range conf.Groups {
+				groups = append(groups, g)
+			}
+			slices.Sort(groups)
+			lines = [][]string{{"GROUP", "HOSTS"}}
+			for _, g := range groups {
+				names, _ := conf.Targets(g)
+				lines = append(lines, []string{g, strings.Join(names, " ")})
+			}
+			fmt.Fprintln(out)
+			printColumns(out, lines, nil)
+			return nil
+		},
+	}
+	cmd.AddCommand(newHostsCheckCmd())
+	return cmd
+}
+
+// hostCheck result of pinging a host
+type hostCheck struct {
+	name    string
+	tls     string
+	elapsed time.Duration
+	err     error
+}
+
+func newHostsCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "validate the config file and ping every host",
+		Long: `validate the config file and ping every host
+
+Problems with the config file are listed first, then every valid host is
+connected to concurrently, bounded by --timeout.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out := cmd.OutOrStdout()
+			conf, cerr := configfile.Load(global.Config)
+			if cerr != nil {
+				fmt.Fprintf(out, "%s:\n%v\n\n", global.Config, cerr)
+			}
+
+			HostMap := conf.Hosts
+			names := conf.Names()
+			results := make([]hostCheck, len(names))
+			var wg sync.WaitGroup
+			for i, name := range names {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results[i] = pingHost(cmd.Context(), name, HostMap[name])
+				}()
+			}
+			wg.Wait()
+
+			var errs []error
+			lines := [][]string{{"NAME", "DRIVER", "ADDRESS", "STATUS", "TLS", "TIME", "ERROR"}}
+			for i, r := range results {
+				h := HostMap[names[i]]
+				status, tls, msg := "ok", r.tls, ""
+				if r.err != nil {
+					status, tls, msg = "fail", "", r.err.Error()
+					errs = append(errs, r.err)
+				} else if tls == "" {
+					tls = "none"
+				}
+				lines = append(lines, []string{r.name, h.Driver, h.Hostname + ":" + strconv.Itoa(h.Port), status, tls,
+					r.elapsed.Round(time.Millisecond).String(), msg})
+			}
+			printColumns(out, lines, func(row, col int, v string) string {
+				switch {
+				case row == 0 || col != 3:
+					return v
+				case results[row-1].err != nil:
+					return failStyle.Render(v)
+				default:
+					return okStyle.Render(v)
+				}
+			})
+
+			if err := failed("hosts", len(names), errs); err != nil {
+				return err
+			}
+			if cerr != nil {
+				return fmt.Errorf("%w file has errors", configfile.ErrConfig)
+			}
+			return nil
+		},
+	}
+}
+
+// pingHost connects to the host and reads the negotiated TLS version,
+// giving up after the global timeout
+func pingHost(ctx context.Context, name string, h configfile.Host) (r hostCheck) {
+	start := time.Now()
+	r.name = name
+	defer func() { r.elapsed = time.Since(start) }()
+
+	db, err := resolveHost(h)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	ctx, cancel := timeoutContext(ctx)
+	defer cancel()
+	conn, err := database.OpenDatabase(ctx, db)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		r.err = fmt.Errorf("timeout after %s", global.Timeout)
+		return r
+	}
+	if err != nil {
+		r.err = err
+		return r
+	}
+	defer conn.Close()
+	r.tls, err = conn.TLSVersion(ctx)
+	if err != nil {
+		// not being allowed to read it does not fail the host
+		r.tls = "unknown"
+	}
+	return r
+}
+
+// printColumns prints lines as left justified columns, style decorates a
+// padded cell
+func printColumns(out io.Writer, lines [][]string, style func(row, col int, v string) string) {
+	var colLens []int
+	for _, line := range lines {
+		for i, v := range line {
+			if i >= len(colLens) {
+				colLens = append(colLens, 0)
+			}
+			colLens[i] = max(colLens[i], len(v))
+		}
+	}
+	for r, line := range lines {
+		for i, v := range line {
+			v = str.LJustLen(v, colLens[i]+1)
+			if style != nil {
+				v = style(r, i, v)
+			}
+			lipgloss.Fprint(out, v)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// hostsWarned reports the invalid config file entries only once per run
+var hostsWarned sync.Once
+
+// loadHosts reads the config file. Invalid host and group entries are left
+// out and reported on stderr, the file is an error only when it cannot be
+// read or parsed at all.
+func loadHosts() (*configfile.Config, error) {
+	conf, err := configfile.Load(global.Config)
+	if err == nil {
+		return conf, nil
+	}
+	if !entryErrors(err) {
+		return nil, fmt.Errorf("%s:\n%w", global.Config, err)
+	}
+	hostsWarned.Do(func() {
+		fmt.Fprintf(os.Stderr, "%s: skipping invalid entries:\n%v\n", global.Config, err)
+	})
+	return conf, nil
+}
+
+// entryErrors reports whether every problem of err is with a single entry
+func entryErrors(err error) bool {
+	errs := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	}
+	for _, e := range errs {
+		var he *configfile.HostError
+		if !errors.As(e, &he) {
+			return false
+		}
+	}
+	return true
+}
+
+// targets returns the host names of a host, group or glob pattern, a
+// connection URI is a single target
+func targets(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, errors.New("no database specified")
+	}
+	if database.IsURI(spec) {
+		return []string{spec}, nil
+	}
+	conf, err := loadHosts()
+	if err != nil {
+		return nil, err
+	}
+	return conf.Targets(spec)
+}
+
+// openHost connects to the config file host or connection URI dbase
+func openHost(ctx context.Context, dbase string) (*database.Database, error) {
+	db, err := hostDatabase(dbase)
+	if err != nil {
+		return nil, err
+	}
+	return database.OpenDatabase(ctx, db)
+}
+
+// hostDatabase returns the connection settings of a config file host or
+// a connection URI
+func hostDatabase(name string) (database.Database, error) {
+	if name == "" {
+		return database.Database{}, errors.New("no database specified")
+	}
+	if database.IsURI(name) {
+		db, err := database.ParseURI(name)
+		if err != nil {
+			return db, err
+		}
+		db.Session.ApplicationName = appName
+		logger.Info("open", "uri", db)
+		return db, nil
+	}
+
+	conf, err := loadHosts()
+	if err != nil {
+		return database.Database{}, err
+	}
+	h, ok := conf.Hosts[name]
+	if !ok {
+		if _, group := conf.Groups[name]; group {
+			return database.Database{}, fmt.Errorf("%s is a group, a single host is needed here", name)
+		}
+		return database.Database{}, fmt.Errorf("database %s not found", name)
+	}
+	logger.Info("open", "host", name, "driver", h.Driver, "database", h.Database)
+	db, err := resolveHost(h)
+	db.Name = name
+	return db, err
+}
+
+// resolveHost resolves the host password into its connection settings
+func resolveHost(h configfile.Host) (database.Database, error) {
+	h, err := h.Resolve()
+	if err != nil {
+		return database.Database{}, err
+	}
+	db := database.Database{
+		Hostname: h.Hostname,
+		Port:     h.Port,
+		Driver:   h.Driver,
+		Database: h.Database,
+		Username: h.Username,
+		Password: h.Password,
+		TLS:      h.TLS,
+		Pool:     h.Pool,
+		Session:  h.Session,
+	}
+	db.Session.ApplicationName = cmp.Or(db.Session.ApplicationName, appName)
+	return db, nil
+}
+
+// hostLabel host name or connection URI without its password
+func hostLabel(name string) string {
+	if database.IsURI(name) {
+		return database.MaskURI(name)
+	}
+	return name
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadHosts(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "config.yml")
+	old := global.Config
+	global.Config = fn
+	t.Cleanup(func() { global.Config = old })
+
+	// a bad entry does not hide the valid hosts
+	if err := os.WriteFile(fn, []byte("pg:\n  hostname: db1\nnohost:\n  driver: pgx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := loadHosts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conf.Hosts["pg"]; !ok || len(conf.Hosts) != 1 {
+		t.Errorf("hosts = %v, want only pg", conf.Names())
+	}
+
+	// a file that cannot be parsed fails
+	if err := os.WriteFile(fn, []byte("pg: [\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHosts(); err == nil {
+		t.Error("unparsable config file should fail")
+	}
+	global.Config = filepath.Join(t.TempDir(), "missing.yml")
+	if _, err := loadHosts(); err == nil {
+		t.Error("missing config file should fail")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/fang"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// SettingsFile name of the settings file, read from the directory of the
+// host config file
+const SettingsFile = "dbtools.yml"
+
+// appName application name reported to the database server unless the
+// host sets its own
+const appName = "dbtools"
+
+// Global settings shared by every command
+type Global struct {
+	Config  string        `mapstructure:"config"`
+	LogFile string        `mapstructure:"logfile"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+var (
+	global   Global
+	settings *viper.Viper
+	logger   = slog.New(slog.NewTextHandler(io.Discard, nil))
+	logFile  *os.File
+)
+
+func main() {
+	// the first interrupt cancels the running queries, a second one kills
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := fang.Execute(ctx, newRootCmd(), fang.WithErrorHandler(errorHandler))
+	stop()
+	// a failed command skips the post run
+	closeLogging()
+	if err != nil {
+		os.Exit(exitCode(err))
+	}
+}
+
+// errorHandler prints errors as is, they carry paths and object names that
+// must not be title cased
+func errorHandler(w io.Writer, styles fang.Styles, err error) {
+	styles.ErrorText = styles.ErrorText.UnsetTransform()
+	fang.DefaultErrorHandler(w, styles, err)
+}
+
+func newRootCmd() *cobra.Command {
+	userConfigDir, _ := os.UserConfigDir()
+
+	cmd := &cobra.Command{
+		Use:   "dbtools",
+		Short: "schema copy, data copy and querying of pg and mssql databases",
+		Long: `schema copy, data copy and querying of pg and mssql databases
+
+Every flag can also be set in the dbtools.yml settings file next to the
+host config file, globally or under the command name, or with DBTOOLS_
+environment variables:
+
+  timeout: 30s
+  copy:
+    jobs: 4
+
+  DBTOOLS_TIMEOUT=30s DBTOOLS_COPY_JOBS=4 dbtools copy ...
+
+` + exitCodes,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return loadGlobal(cmd)
+		},
+		PersistentPostRun: func(*cobra.Command, []string) {
+			closeLogging()
+		},
+	}
+	cmd.PersistentFlags().StringP("config", "c", filepath.Join(userConfigDir, "dbtools", "config.yml"), "host config file")
+	cmd.PersistentFlags().String("logfile", "", "log file, no logging when empty")
+	cmd.PersistentFlags().Duration("timeout", 10*time.Second, "query timeout")
+
+	cmd.AddCommand(
+		newCopyCmd(),
+		newQueryCmd(),
+		newDiffCmd(),
+		newSnapshotCmd(),
+		newRestoreCmd(),
+		newApplyCmd(),
+		newMigrateCmd(),
+		newHostsCmd(),
+	)
+	return cmd
+}
+
+// loadGlobal reads the settings file and the global settings, then sets up
+// logging
+func loadGlobal(cmd *cobra.Command) error {
+	v := newViper("DBTOOLS")
+	for _, name := range []string{"config", "logfile", "timeout"} {
+		if err := v.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+			return err
+		}
+	}
+
+	v.SetConfigFile(filepath.Join(filepath.Dir(v.GetString("config")), SettingsFile))
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := v.Unmarshal(&global); err != nil {
+		return err
+	}
+	settings = v
+	return setupLogging(global.LogFile)
+}
+
+// loadConfig binds the command flags, DBTOOLS_<SECTION>_ environment
+// variables and the section of the settings file into out
+func loadConfig(cmd *cobra.Command, section string, out any) error {
+	v := newViper("DBTOOLS_" + strings.ToUpper(section))
+	if sub := settings.GetStringMap(section); len(sub) > 0 {
+		if err := v.MergeConfigMap(sub); err != nil {
+			return err
+		}
+	}
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err == nil {
+			err = v.BindPFlag(f.Name, f)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return v.Unmarshal(out)
+}
+
+func newViper(envPrefix string) *viper.Viper {
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+	return v
+}
+
+// timeoutContext ctx bounded by the global timeout
+func timeoutContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, global.Timeout)
+}
+
+func setupLogging(logName string) error {
+	if logName == "" {
+		return nil
+	}
+	f, err := os.OpenFile(logName, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	logger = slog.New(slog.NewTextHandler(f, nil))
+	logFile = f
+	return nil
+}
+
+// closeLogging closes the log file, later messages are discarded
+func closeLogging() {
+	if logFile == nil {
+		return
+	}
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	logFile.Close()
+	logFile = nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/migrate"
+	"github.com/spf13/cobra"
+)
+
+// MigrateConfig migrate command settings
+type MigrateConfig struct {
+	DBase string `mapstructure:"db"`
+	Dir   string `mapstructure:"dir"`
+	Steps int    `mapstructure:"steps"`
+	Debug bool   `mapstructure:"dry-run"`
+}
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "apply numbered sql migrations and track them in a history table",
+		Long: `apply numbered sql migrations and track them in a history table
+
+Migrations are <version>_<name>.up.sql files with an optional matching
+.down.sql, in --dir. The applied versions, up script checksums and times are
+kept in the ` + migrate.Table + ` table of the database, created on first use.
+Every migration runs in one transaction together with its history row, a
+file starting with ` + migrate.NoTransaction + ` runs its statements one at
+a time instead, for those that cannot run in a transaction. Files are split
+into batches like dbtools apply does: mssql at GO lines, the others at
+semicolons.
+
+dbtools diff --emit-migration writes the next migration from a diff.`,
+		Example: `  dbtools migrate status -d dev
+  dbtools migrate up -d dev --dir migrations
+  dbtools migrate down -d dev --steps 2`,
+	}
+	fs := cmd.PersistentFlags()
+	fs.StringP("db", "d", "", "config host name or connection URI")
+	fs.String("dir", "migrations", "directory of the migration files")
+	cmd.AddCommand(newMigrateUpCmd(), newMigrateDownCmd(), newMigrateStatusCmd())
+	return cmd
+}
+
+func newMigrateUpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "apply the pending migrations in version order",
+		Long: `apply the pending migrations in version order
+
+The first failed migration stops the run. Nothing runs while an applied
+migration's up script has changed since, see dbtools migrate status.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := MigrateConfig{}
+			if err := loadConfig(cmd, "migrate", &config); err != nil {
+				return err
+			}
+			return runMigrate(cmd, &config, true)
+		},
+	}
+	cmd.Flags().Int("steps", 0, "apply at most this many migrations, 0 for all")
+	cmd.Flags().BoolP("dry-run", "n", false, "print the batches instead of running them")
+	return cmd
+}
+
+func newMigrateDownCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "revert the last applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := MigrateConfig{}
+			if err := loadConfig(cmd, "migrate", &config); err != nil {
+				return err
+			}
+			return runMigrate(cmd, &config, false)
+		},
+	}
+	cmd.Flags().Int("steps", 1, "revert this many migrations")
+	cmd.Flags().BoolP("dry-run", "n", false, "print the batches instead of running them")
+	return cmd
+}
+
+func newMigrateStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "list the migrations and whether they are applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := MigrateConfig{}
+			if err := loadConfig(cmd, "migrate", &config); err != nil {
+				return err
+			}
+			db, states, err := migrateState(cmd.Context(), &config, false)
+			if err != nil {
+				return err
+			}
+			db.Close()
+			lines := [][]string{{"VERSION", "NAME", "STATUS", "APPLIED"}}
+			for _, s := range states {
+				applied := ""
+				if s.Record != nil {
+					applied = s.Record.AppliedAt.Local().Format(time.DateTime)
+				}
+				lines = append(lines, []string{strconv.FormatInt(s.Version, 10), s.Name, s.Status(), applied})
+			}
+			printColumns(cmd.OutOrStdout(), lines, migrateStyle)
+			return nil
+		},
+	}
+}
+
+// migrateState opens the database and merges its history with the
+// migration files, creating the history table when create is set
+func migrateState(ctx context.Context, config *MigrateConfig, create bool) (*database.Database, []migrate.State, error) {
+	migs, err := migrate.Load(config.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := openHost(ctx, config.DBase)
+	if err != nil {
+		return nil, nil, err
+	}
+	octx, cancel := timeoutContext(ctx)
+	defer cancel()
+	if create {
+		if err := migrate.Init(octx, db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+	hist, err := migrate.History(octx, db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, migrate.Status(migs, hist), nil
+}
+
+// runMigrate applies the pending migrations, or reverts the applied ones
+// from the last when up is false
+func runMigrate(cmd *cobra.Command, config *MigrateConfig, up bool) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	db, states, err := migrateState(ctx, config, !config.Debug)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var todo []migrate.State
+	for _, s := range states {
+		switch {
+		case up && s.Status() == migrate.Modified:
+			return fmt.Errorf("%s was changed after it was applied", s.File()+".up.sql")
+		case up && s.Status() == migrate.Pending, !up && s.Record != nil:
+			todo = append(todo, s)
+		}
+	}
+	if !up {
+		slices.Reverse(todo)
+	}
+	if config.Steps > 0 && len(todo) > config.Steps {
+		todo = todo[:config.Steps]
+	}
+	if len(todo) == 0 {
+		fmt.Fprintln(out, "nothing to do")
+		return nil
+	}
+
+	if config.Debug {
+		for _, s := range todo {
+			printMigration(out, db.Driver, s.Migration, up)
+		}
+		return nil
+	}
+
+	var errs []error
+	lines := [][]string{{"VERSION", "NAME", "STATUS", "ERROR"}}
+	for _, s := range todo {
+		version := strconv.FormatInt(s.Version, 10)
+		if len(errs) > 0 {
+			lines = append(lines, []string{version, s.Name, "skipped", ""})
+			continue
+		}
+		err := ctx.Err()
+		if err == nil {
+			octx, cancel := timeoutContext(ctx)
+			if up {
+				err = migrate.Up(octx, db, s.Migration)
+			} else if s.Status() == migrate.Missing {
+				err = errors.New("no migration files")
+			} else {
+				err = migrate.Down(octx, db, s.Migration)
+			}
+			cancel()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			lines = append(lines, []string{version, s.Name, "failed", err.Error()})
+			continue
+		}
+		status := "applied"
+		if !up {
+			status = "reverted"
+		}
+		lines = append(lines, []string{version, s.Name, status, ""})
+	}
+	printColumns(out, lines, migrateStyle)
+	return failed("migrations", len(todo), errs)
+}
+
+// printMigration prints the batches of the up or down script of m
+func printMigration(out io.Writer, driver string, m migrate.Migration, up bool) {
+	script, suffix := m.Up, ".up.sql"
+	if !up {
+		script, suffix = m.Down, ".down.sql"
+	}
+	fmt.Fprintf(out, "-- %s\n", m.File()+suffix)
+	printScript(out, driver, script)
+}
+
+// migrateStyle colors the status column of the migrate tables
+func migrateStyle(row, col int, v string) string {
+	if row == 0 || col != 2 {
+		return v
+	}
+	switch strings.TrimSpace(v) {
+	case "applied", "reverted":
+		return okStyle.Render(v)
+	case migrate.Pending, "skipped":
+		return canceledStyle.Render(v)
+	}
+	return failStyle.Render(v)
+}
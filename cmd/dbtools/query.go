@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/querylib"
+	"github.com/ppreeper/dbtools/pkg/render"
+	"github.com/ppreeper/str"
+	"github.com/spf13/cobra"
+)
+
+// QueryConfig query command settings
+type QueryConfig struct {
+	DBase       string            `mapstructure:"db"`
+	Query       string            `mapstructure:"query"`
+	Params      map[string]string `mapstructure:"param"`
+	FieldSep    string            `mapstructure:"field-sep"`
+	Format      string            `mapstructure:"format"`
+	Timer       bool              `mapstructure:"timer"`
+	Watch       time.Duration     `mapstructure:"watch"`
+	Key         string            `mapstructure:"key"`
+	Null        string            `mapstructure:"null"`
+	Binary      string            `mapstructure:"binary"`
+	Wrap        bool              `mapstructure:"wrap"`
+	Expanded    bool              `mapstructure:"expanded"`
+	Pager       bool              `mapstructure:"pager"`
+	Explain     bool              `mapstructure:"explain"`
+	Analyze     bool              `mapstructure:"analyze"`
+	ExplainOut  string            `mapstructure:"explain-out"`
+	ExplainDiff string            `mapstructure:"explain-diff"`
+	Jobs        int               `mapstructure:"jobs"`
+}
+
+func (o *QueryConfig) renderer() render.Renderer {
+	return render.Renderer{Null: o.Null, Binary: o.Binary}
+}
+
+func newQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query [sql]",
+		Short: "run a query and print the result",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := QueryConfig{}
+			if err := loadConfig(cmd, "query", &opts); err != nil {
+				return err
+			}
+			stmt := opts.Query
+			if len(args) > 0 {
+				stmt = args[0]
+			}
+			if stmt == "" {
+				return errors.New("no query specified")
+			}
+			return execQuery(cmd.Context(), &opts, stmt)
+		},
+	}
+	fs := cmd.PersistentFlags()
+	fs.StringP("db", "d", "", "config host, group or connection URI")
+	fs.StringP("field-sep", "F", ";", "field separator")
+	fs.StringP("format", "o", "table", "output format: table, csv, json")
+	fs.Bool("timer", false, "print the query time")
+	fs.String("null", "NULL", "text printed for NULL values")
+	fs.String("binary", render.BinaryHex, "binary value encoding: hex, base64")
+	fs.Bool("wrap", false, "wrap wide columns instead of truncating them")
+	fs.BoolP("expanded", "x", false, "expanded display, one line per column")
+	fs.Bool("pager", true, "page output through $PAGER when it exceeds the screen")
+	fs.Bool("explain", false, "show the execution plan instead of running the query")
+	fs.Bool("analyze", false, "explain with actual rows and timing, executes the query in a rolled back transaction")
+	fs.String("explain-out", "", "save the raw plan to file")
+	fs.String("explain-diff", "", "compare the plan with the same query on another database")
+	fs.DurationP("watch", "w", 0, "re-run the query at this interval, highlighting changes")
+	fs.String("key", "", "watch row key columns, comma separated (default first column)")
+	fs.IntP("jobs", "j", 8, "hosts queried concurrently when --db is a group")
+	cmd.Flags().StringP("query", "q", "", "sql query")
+
+	cmd.AddCommand(newQueryRunCmd(), newQueryListCmd())
+	return cmd
+}
+
+func newQueryRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "run a saved query from the query library",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := QueryConfig{}
+			if err := loadConfig(cmd, "query", &opts); err != nil {
+				return err
+			}
+			q, err := querylib.Load(querylib.Dir(global.Config), args[0])
+			if err != nil {
+				return err
+			}
+
+			// front-matter defaults apply unless given on the command line
+			if !cmd.Flags().Changed("db") && q.Host != "" {
+				opts.DBase = q.Host
+			}
+			if !cmd.Flags().Changed("format") && q.Format != "" {
+				opts.Format = q.Format
+			}
+
+			stmt, err := q.Render(opts.Params)
+			if err != nil {
+				return err
+			}
+			return execQuery(cmd.Context(), &opts, stmt)
+		},
+	}
+	cmd.Flags().StringToStringP("param", "p", nil, "query parameter name=value, repeatable")
+	return cmd
+}
+
+func newQueryListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list the saved queries in the query library",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			qq, err := querylib.List(querylib.Dir(global.Config))
+			if err != nil {
+				return err
+			}
+
+			nameLen := len("NAME")
+			hostLen := len("HOST")
+			for _, q := range qq {
+				nameLen = max(nameLen, len(q.Name))
+				hostLen = max(hostLen, len(q.Host))
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, str.LJustLen("NAME", nameLen), str.LJustLen("HOST", hostLen), "DESCRIPTION")
+			for _, q := range qq {
+				desc := q.Description
+				for _, p := range q.Params {
+					desc += " --param " + p.Name + "=" + p.Default
+				}
+				fmt.Fprintln(out, str.LJustLen(q.Name, nameLen), str.LJustLen(q.Host, hostLen), strings.TrimSpace(desc))
+			}
+			return nil
+		},
+	}
+}
+
+func execQuery(ctx context.Context, opts *QueryConfig, stmt string) error {
+	names, err := targets(opts.DBase)
+	if err != nil {
+		return err
+	}
+	if len(names) > 1 {
+		return fanOutQuery(ctx, opts, names, stmt)
+	}
+
+	sdb, err := openHost(ctx, opts.DBase)
+	if err != nil {
+		return err
+	}
+	defer sdb.Close()
+
+	if opts.Explain || opts.ExplainDiff != "" {
+		return explainQuery(ctx, opts, sdb, stmt)
+	}
+
+	if opts.Watch > 0 {
+		return watchQuery(ctx, opts, sdb, stmt)
+	}
+
+	start := time.Now()
+	colNames, colTypes, dataSet, err := queryData(ctx, sdb, stmt)
+	if err != nil {
+		return err
+	}
+	return printResult(opts, stmt, colNames, colTypes, dataSet, time.Since(start))
+}
+
+// printResult prints the query result in the output format, paged when it
+// exceeds the screen
+func printResult(opts *QueryConfig, stmt string, colNames, colTypes []string, dataSet [][]any, elapsed time.Duration) error {
+	var err error
+	width, height := terminalSize()
+	var out bytes.Buffer
+	r := opts.renderer()
+	switch opts.Format {
+	case "csv":
+		err = printCSV(&out, colNames, rowStrings(r, colTypes, dataSet))
+	case "json":
+		err = printJSON(&out, r, colNames, colTypes, dataSet)
+	default:
+		layout := tableLayout{width: width, wrap: opts.Wrap, expanded: opts.Expanded}
+		printData(&out, colNames, rowStrings(r, colTypes, dataSet), opts.FieldSep, layout)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.Timer {
+		fmt.Fprintf(&out, "----------\nquery: %s\ntime: %s\n", stmt, elapsed.String())
+	}
+	if !opts.Pager {
+		height = 0
+	}
+	pageOutput(out.Bytes(), height)
+	return nil
+}
+
+// queryData returns the column names, database column types and rows of stmt
+func queryData(ctx context.Context, sdb *database.Database, stmt string) (colNames, colTypes []string, dataSet [][]any, err error) {
+	ctx, cancel := timeoutContext(ctx)
+	defer cancel()
+	rows, err := sdb.DB.QueryxContext(ctx, stmt)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	colNames, err = rows.Columns()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("query columns: %w", err)
+	}
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("query column types: %w", err)
+	}
+	for _, ct := range cts {
+		colTypes = append(colTypes, ct.DatabaseTypeName())
+	}
+
+	for rows.Next() {
+		cols := make([]any, len(colNames))
+		colPtrs := make([]any, len(colNames))
+		for i := range cols {
+			colPtrs[i] = &cols[i]
+		}
+		if err := rows.Scan(colPtrs...); err != nil {
+			return nil, nil, nil, fmt.Errorf("query scan: %w", err)
+		}
+		dataSet = append(dataSet, cols)
+	}
+	return colNames, colTypes, dataSet, rows.Err()
+}
+
+// rowStrings renders every value of the data set
+func rowStrings(r render.Renderer, colTypes []string, dataSet [][]any) [][]string {
+	lines := make([][]string, 0, len(dataSet))
+	for _, row := range dataSet {
+		line := make([]string, len(row))
+		for k, v := range row {
+			line[k] = r.Value(v, colTypes[k])
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func printCSV(out io.Writer, colNames []string, lines [][]string) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(colNames); err != nil {
+		return err
+	}
+	if err := w.WriteAll(lines); err != nil {
+		return err
+	}
+	return nil
+}
+
+func printJSON(out io.Writer, r render.Renderer, colNames, colTypes []string, dataSet [][]any) error {
+	objs := make([]map[string]any, 0, len(dataSet))
+	for _, row := range dataSet {
+		rowMap := make(map[string]any)
+		for k, v := range row {
+			rowMap[colNames[k]] = r.JSONValue(v, colTypes[k])
+		}
+		objs = append(objs, rowMap)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objs)
+}
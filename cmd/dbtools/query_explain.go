@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	"github.com/ppreeper/dbtools/pkg/explain"
+)
+
+// explainQuery prints the execution plan of stmt, or the difference to
+// the plan on the --explain-diff database
+func explainQuery(ctx context.Context, opts *QueryConfig, sdb *database.Database, stmt string) error {
+	plan, err := explain.Capture(ctx, sdb, stmt, opts.Analyze)
+	if err != nil {
+		return err
+	}
+	if err := savePlan(opts.ExplainOut, plan); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if opts.ExplainDiff == "" {
+		explain.Render(&out, plan)
+	} else {
+		ddb, err := openHost(ctx, opts.ExplainDiff)
+		if err != nil {
+			return err
+		}
+		defer ddb.Close()
+		other, err := explain.Capture(ctx, ddb, stmt, opts.Analyze)
+		if err != nil {
+			return err
+		}
+		if opts.ExplainOut != "" {
+			// a connection URI makes no file name
+			suffix := opts.ExplainDiff
+			if database.IsURI(suffix) {
+				suffix = "diff"
+			}
+			ext := filepath.Ext(opts.ExplainOut)
+			if err := savePlan(strings.TrimSuffix(opts.ExplainOut, ext)+"."+suffix+ext, other); err != nil {
+				return err
+			}
+		}
+		explain.Diff(&out, plan, other, hostLabel(opts.DBase), hostLabel(opts.ExplainDiff))
+	}
+
+	_, height := terminalSize()
+	if !opts.Pager {
+		height = 0
+	}
+	pageOutput(out.Bytes(), height)
+	return nil
+}
+
+// savePlan writes the raw plan to fn
+func savePlan(fn string, plan *explain.Plan) error {
+	if fn == "" {
+		return nil
+	}
+	if filepath.Ext(fn) == "" {
+		fn += plan.Ext()
+	}
+	return os.WriteFile(fn, plan.Raw, 0o666)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// hostResult query result from one host of a group
+type hostResult struct {
+	colNames []string
+	colTypes []string
+	dataSet  [][]any
+	err      error
+}
+
+// fanOutQuery runs stmt on every host and prints the combined result with
+// a leading host column. Hosts that fail or return other columns are
+// reported and left out.
+func fanOutQuery(ctx context.Context, opts *QueryConfig, names []string, stmt string) error {
+	if opts.Watch > 0 || opts.Explain || opts.ExplainDiff != "" {
+		return errors.New("--watch and --explain need a single host")
+	}
+
+	start := time.Now()
+	results := make([]hostResult, len(names))
+	sem := make(chan struct{}, max(opts.Jobs, 1))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = queryHost(ctx, name, stmt)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var colNames, colTypes []string
+	var dataSet [][]any
+	var errs []error
+	for i, r := range results {
+		if r.err == nil && colNames != nil && !slices.Equal(colNames[1:], r.colNames) {
+			r.err = fmt.Errorf("columns %v differ from %v", r.colNames, colNames[1:])
+		}
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", hostLabel(names[i]), r.err)
+			errs = append(errs, r.err)
+			continue
+		}
+		if colNames == nil {
+			colNames = append([]string{"host"}, r.colNames...)
+			colTypes = append([]string{"TEXT"}, r.colTypes...)
+		}
+		for _, row := range r.dataSet {
+			dataSet = append(dataSet, append([]any{names[i]}, row...))
+		}
+	}
+	if colNames != nil {
+		if err := printResult(opts, stmt, colNames, colTypes, dataSet, elapsed); err != nil {
+			return err
+		}
+	}
+	return failed("hosts", len(names), errs)
+}
+
+func queryHost(ctx context.Context, name, stmt string) hostResult {
+	db, err := openHost(ctx, name)
+	if err != nil {
+		return hostResult{err: err}
+	}
+	defer db.Close()
+	colNames, colTypes, dataSet, err := queryData(ctx, db, stmt)
+	return hostResult{colNames: colNames, colTypes: colTypes, dataSet: dataSet, err: err}
+}
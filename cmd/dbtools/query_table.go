@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/mattn/go-runewidth"
+)
+
+// minColWidth narrowest a column is truncated to before switching to
+// expanded display
+const minColWidth = 4
+
+// tableLayout how a result table is fitted to the terminal
+type tableLayout struct {
+	width    int // terminal width, 0 when not a terminal
+	wrap     bool
+	expanded bool
+}
+
+// terminalSize returns the size of stdout, 0 when it is not a terminal
+func terminalSize() (width, height int) {
+	fd := os.Stdout.Fd()
+	if !term.IsTerminal(fd) {
+		return 0, 0
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return 0, 0
+	}
+	return width, height
+}
+
+func printData(w io.Writer, colNames []string, lines [][]string, fieldSep string, layout tableLayout) {
+	colLens := columnWidths(colNames, lines)
+	if layout.expanded {
+		printExpanded(w, colNames, lines)
+		return
+	}
+	if layout.width > 0 {
+		var fits bool
+		colLens, fits = fitWidths(colLens, runewidth.StringWidth(fieldSep), layout.width)
+		if !fits {
+			printExpanded(w, colNames, lines)
+			return
+		}
+	}
+	// print headers
+	fmt.Fprintln(w, joinLine(colNames, colLens, fieldSep))
+	// print line items
+	for _, line := range lines {
+		if layout.wrap {
+			for _, l := range wrapLine(line, colLens, fieldSep) {
+				fmt.Fprintln(w, l)
+			}
+		} else {
+			fmt.Fprintln(w, joinLine(line, colLens, fieldSep))
+		}
+	}
+}
+
+// printExpanded prints each row vertically, one line per column
+func printExpanded(w io.Writer, colNames []string, lines [][]string) {
+	nameLen := 0
+	for _, c := range colNames {
+		nameLen = max(nameLen, runewidth.StringWidth(c))
+	}
+	for i, line := range lines {
+		fmt.Fprintf(w, "-[ RECORD %d ]%s\n", i+1, strings.Repeat("-", nameLen))
+		for k, v := range line {
+			fmt.Fprintf(w, "%s | %s\n", runewidth.FillRight(colNames[k], nameLen), v)
+		}
+	}
+}
+
+// columnWidths get maximum field display widths
+func columnWidths(colNames []string, lines ...[][]string) []int {
+	colLens := make([]int, len(colNames))
+	for k, v := range colNames {
+		colLens[k] = runewidth.StringWidth(v)
+	}
+	for _, ll := range lines {
+		for _, line := range ll {
+			for k, vs := range line {
+				colLens[k] = max(colLens[k], runewidth.StringWidth(vs))
+			}
+		}
+	}
+	return colLens
+}
+
+// fitWidths narrows the widest columns until the line fits width, reports
+// false when it cannot fit
+func fitWidths(colLens []int, sepWidth, width int) ([]int, bool) {
+	fit := slices.Clone(colLens)
+	total := sepWidth * (len(fit) - 1)
+	for _, l := range fit {
+		total += l
+	}
+	for total > width {
+		widest := 0
+		for k := range fit {
+			if fit[k] > fit[widest] {
+				widest = k
+			}
+		}
+		if fit[widest] <= minColWidth {
+			return fit, false
+		}
+		fit[widest]--
+		total--
+	}
+	return fit, true
+}
+
+// cell pads or truncates v to width display cells
+func cell(v string, width int) string {
+	if runewidth.StringWidth(v) > width {
+		v = runewidth.Truncate(v, width, "…")
+	}
+	return runewidth.FillRight(v, width)
+}
+
+// joinLine pads and joins the fields of a line
+func joinLine(fields []string, colLens []int, fieldSep string) string {
+	line := ""
+	for k, v := range fields {
+		line += cell(v, colLens[k])
+		if k < len(fields)-1 {
+			line += fieldSep
+		}
+	}
+	return line
+}
+
+// wrapLine wraps the fields of a line over as many lines as needed
+func wrapLine(fields []string, colLens []int, fieldSep string) []string {
+	parts := make([][]string, len(fields))
+	rows := 1
+	for k, v := range fields {
+		parts[k] = strings.Split(runewidth.Wrap(v, colLens[k]), "\n")
+		rows = max(rows, len(parts[k]))
+	}
+	lines := make([]string, rows)
+	for i := range lines {
+		fields := make([]string, len(parts))
+		for k, p := range parts {
+			if i < len(p) {
+				fields[k] = p[i]
+			}
+		}
+		lines[i] = joinLine(fields, colLens, fieldSep)
+	}
+	return lines
+}
+
+// pageOutput writes out to stdout, through $PAGER when it has more lines
+// than height
+func pageOutput(out []byte, height int) {
+	if height > 0 && bytes.Count(out, []byte("\n")) >= height {
+		pager := os.Getenv("PAGER")
+		if pager == "" {
+			pager = "less -FRSX"
+		}
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = bytes.NewReader(out)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		// once the pager runs it owns the output, even when it fails; sh
+		// exits 126 or 127 when it cannot run the pager at all
+		err := cmd.Start()
+		if err == nil {
+			var ee *exec.ExitError
+			if err = cmd.Wait(); !errors.As(err, &ee) || ee.ExitCode() != 126 && ee.ExitCode() != 127 {
+				return
+			}
+		}
+	}
+	os.Stdout.Write(out)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFitWidths(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		colLens  []int
+		width    int
+		want     []int
+		wantFits bool
+	}{
+		{"fits", []int{3, 5}, 20, []int{3, 5}, true},
+		{"exact", []int{3, 5}, 11, []int{3, 5}, true},
+		{"narrows widest", []int{3, 20}, 16, []int{3, 10}, true},
+		{"narrows both", []int{12, 12}, 19, []int{8, 8}, true},
+		{"too narrow", []int{5, 5, 5}, 12, []int{4, 4, 4}, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, fits := fitWidths(c.colLens, 3, c.width)
+			if !slices.Equal(got, c.want) || fits != c.wantFits {
+				t.Errorf("fitWidths(%v, 3, %d) = %v, %v, want %v, %v", c.colLens, c.width, got, fits, c.want, c.wantFits)
+			}
+		})
+	}
+}
+
+func TestCell(t *testing.T) {
+	for _, c := range []struct {
+		v     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"日本", 6, "日本  "},
+		{"日本語", 6, "日本語"},
+		{"日本語テキスト", 5, "日本…"},
+		// a wide rune that does not fit is padded
+		{"日本語", 4, "日… "},
+	} {
+		if got := cell(c.v, c.width); got != c.want {
+			t.Errorf("cell(%q, %d) = %q, want %q", c.v, c.width, got, c.want)
+		}
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		fields  []string
+		colLens []int
+		want    []string
+	}{
+		{"one line", []string{"a", "b"}, []int{3, 3}, []string{"a   | b  "}},
+		{"wraps", []string{"id", "hello world"}, []int{2, 5}, []string{
+			"id | hello",
+			"   |  worl",
+			"   | d    ",
+		}},
+		{"wide runes", []string{"日本語テキスト", "x"}, []int{6, 1}, []string{
+			"日本語 | x",
+			"テキス |  ",
+			"ト     |  ",
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := wrapLine(c.fields, c.colLens, " | "); !slices.Equal(got, c.want) {
+				t.Errorf("wrapLine = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+// row states between two watch runs
+const (
+	rowSame = iota
+	rowAdded
+	rowChanged
+	rowRemoved
+)
+
+var (
+	addedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Green)
+	changedStyle = lipgloss.NewStyle().Foreground(lipgloss.Yellow)
+	removedStyle = lipgloss.NewStyle().Foreground(lipgloss.Red).Strikethrough(true)
+	headerStyle  = lipgloss.NewStyle().Bold(true)
+)
+
+// watchRow a result row and its state compared to the previous run
+type watchRow struct {
+	fields  []string
+	state   int
+	changed []bool
+}
+
+// watchSnapshot result rows of a run by row key
+type watchSnapshot struct {
+	colNames []string
+	order    []string
+	rows     map[string][]string
+}
+
+// watchQuery re-runs stmt every opts.Watch and redraws the result until ctx
+// is canceled
+func watchQuery(ctx context.Context, opts *QueryConfig, sdb *database.Database, stmt string) error {
+	ticker := time.NewTicker(opts.Watch)
+	defer ticker.Stop()
+
+	var prev *watchSnapshot
+	for {
+		start := time.Now()
+		colNames, colTypes, dataSet, err := queryData(ctx, sdb, stmt)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		elapsed := time.Since(start)
+
+		keyCols, err := keyColumns(colNames, opts.Key)
+		if err != nil {
+			return err
+		}
+		if prev != nil && !slices.Equal(prev.colNames, colNames) {
+			prev = nil
+		}
+		rows, cur := diffRows(prev, colNames, rowStrings(opts.renderer(), colTypes, dataSet), keyCols)
+		drawWatch(opts, stmt, colNames, rows, elapsed)
+		prev = cur
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// keyColumns returns the indexes of the row key columns, the first column
+// without a key, none when there are no columns
+func keyColumns(colNames []string, key string) ([]int, error) {
+	if key == "" {
+		if len(colNames) == 0 {
+			return nil, nil
+		}
+		return []int{0}, nil
+	}
+	var keyCols []int
+	for _, k := range strings.Split(key, ",") {
+		idx := slices.IndexFunc(colNames, func(c string) bool {
+			return strings.EqualFold(c, strings.TrimSpace(k))
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("key column %s not found", k)
+		}
+		keyCols = append(keyCols, idx)
+	}
+	return keyCols, nil
+}
+
+// diffRows compares the lines of this run against the previous snapshot
+func diffRows(prev *watchSnapshot, colNames []string, lines [][]string, keyCols []int) ([]watchRow, *watchSnapshot) {
+	cur := &watchSnapshot{colNames: colNames, rows: make(map[string][]string)}
+	seen := make(map[string]int)
+	rows := make([]watchRow, 0, len(lines))
+	for _, line := range lines {
+		var kk []string
+		for _, k := range keyCols {
+			kk = append(kk, line[k])
+		}
+		key := strings.Join(kk, "\x00")
+		// duplicate keys are matched by occurrence
+		if n := seen[key]; n > 0 {
+			seen[key]++
+			key += "#" + strconv.Itoa(n)
+		} else {
+			seen[key] = 1
+		}
+		cur.order = append(cur.order, key)
+		cur.rows[key] = line
+
+		row := watchRow{fields: line, state: rowSame}
+		if prev != nil {
+			old, ok := prev.rows[key]
+			if !ok {
+				row.state = rowAdded
+			} else {
+				row.changed = make([]bool, len(line))
+				for k := range line {
+					if line[k] != old[k] {
+						row.changed[k] = true
+						row.state = rowChanged
+					}
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	if prev != nil {
+		for _, key := range prev.order {
+			if _, ok := cur.rows[key]; !ok {
+				rows = append(rows, watchRow{fields: prev.rows[key], state: rowRemoved})
+			}
+		}
+	}
+	return rows, cur
+}
+
+// drawWatch clears the screen and prints the highlighted result
+func drawWatch(opts *QueryConfig, stmt string, colNames []string, rows []watchRow, elapsed time.Duration) {
+	var lines [][]string
+	counts := make(map[int]int)
+	for _, r := range rows {
+		lines = append(lines, r.fields)
+		counts[r.state]++
+	}
+	colLens := columnWidths(colNames, lines)
+	if width, _ := terminalSize(); width > 0 {
+		colLens, _ = fitWidths(colLens, runewidth.StringWidth(opts.FieldSep), width)
+	}
+
+	title, _, _ := strings.Cut(strings.TrimSpace(stmt), "\n")
+	fmt.Print(ansi.CursorHomePosition + ansi.EraseEntireScreen)
+	fmt.Printf("Every %s on %s: %s\n", opts.Watch, hostLabel(opts.DBase), title)
+	lipgloss.Printf("%s  rows: %d  %s  %s  %s  time: %s\n\n",
+		time.Now().Format(time.DateTime),
+		len(rows)-counts[rowRemoved],
+		addedStyle.Render(fmt.Sprintf("+%d", counts[rowAdded])),
+		changedStyle.Render(fmt.Sprintf("~%d", counts[rowChanged])),
+		removedStyle.Render(fmt.Sprintf("-%d", counts[rowRemoved])),
+		elapsed,
+	)
+
+	lipgloss.Println(headerStyle.Render(joinLine(colNames, colLens, opts.FieldSep)))
+	for _, r := range rows {
+		switch r.state {
+		case rowAdded:
+			lipgloss.Println(addedStyle.Render(joinLine(r.fields, colLens, opts.FieldSep)))
+		case rowRemoved:
+			lipgloss.Println(removedStyle.Render(joinLine(r.fields, colLens, opts.FieldSep)))
+		case rowChanged:
+			line := ""
+			for k, v := range r.fields {
+				c := cell(v, colLens[k])
+				if r.changed[k] {
+					c = changedStyle.Bold(true).Render(c)
+				}
+				line += c
+				if k < len(r.fields)-1 {
+					line += opts.FieldSep
+				}
+			}
+			lipgloss.Println(line)
+		default:
+			fmt.Println(joinLine(r.fields, colLens, opts.FieldSep))
+		}
+	}
+}
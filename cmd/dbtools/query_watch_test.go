@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeyColumns(t *testing.T) {
+	cols := []string{"id", "Name", "qty"}
+	for _, c := range []struct {
+		cols []string
+		key  string
+		want []int
+	}{
+		{cols, "", []int{0}},
+		{cols, "name", []int{1}},
+		{cols, "qty, ID", []int{2, 0}},
+		{nil, "", nil},
+	} {
+		got, err := keyColumns(c.cols, c.key)
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("keyColumns(%v, %q) = %v, %v, want %v", c.cols, c.key, got, err, c.want)
+		}
+	}
+	if _, err := keyColumns(cols, "missing"); err == nil {
+		t.Error("unknown key column should fail")
+	}
+}
+
+func TestDiffRows(t *testing.T) {
+	cols := []string{"id", "name", "qty"}
+	first := [][]string{{"1", "a", "5"}, {"2", "b", "6"}, {"3", "c", "7"}}
+	rows, prev := diffRows(nil, cols, first, []int{0})
+	for _, r := range rows {
+		if r.state != rowSame {
+			t.Errorf("first run %v: state %d, want same", r.fields, r.state)
+		}
+	}
+
+	second := [][]string{{"1", "a", "5"}, {"3", "c", "9"}, {"4", "d", "1"}}
+	rows, _ = diffRows(prev, cols, second, []int{0})
+	want := []struct {
+		id    string
+		state int
+	}{{"1", rowSame}, {"3", rowChanged}, {"4", rowAdded}, {"2", rowRemoved}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].fields[0] != w.id || rows[i].state != w.state {
+			t.Errorf("row %d: got %v state %d, want id %s state %d", i, rows[i].fields, rows[i].state, w.id, w.state)
+		}
+	}
+	if !slices.Equal(rows[1].changed, []bool{false, false, true}) {
+		t.Errorf("changed cells %v, want only qty", rows[1].changed)
+	}
+}
+
+func TestDiffRowsNoKey(t *testing.T) {
+	// without a key column the rows are matched by occurrence
+	first := [][]string{{"x"}, {"x"}}
+	_, prev := diffRows(nil, []string{"v"}, first, nil)
+	rows, _ := diffRows(prev, []string{"v"}, [][]string{{"x"}, {"y"}}, nil)
+	if len(rows) != 2 || rows[0].state != rowSame || rows[1].state != rowChanged {
+		t.Errorf("got %+v, want the first same and the second changed", rows)
+	}
+
+	// duplicate keys are matched by occurrence too
+	_, prev = diffRows(nil, []string{"k", "v"}, [][]string{{"1", "a"}, {"1", "b"}}, []int{0})
+	rows, _ = diffRows(prev, []string{"k", "v"}, [][]string{{"1", "a"}}, []int{0})
+	if len(rows) != 2 || rows[0].state != rowSame || rows[1].state != rowRemoved || rows[1].fields[1] != "b" {
+		t.Errorf("got %+v, want the second duplicate removed", rows)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// RestoreConfig restore command settings
+type RestoreConfig struct {
+	DBase string `mapstructure:"db"`
+	Debug bool   `mapstructure:"dry-run"`
+}
+
+// restoreOrder objects are restored tables first, then what depends on them
+var restoreOrder = []string{"t", "v", "r", "i"}
+
+func newRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore <dir>",
+		Short: "restore the objects saved by copy --backup",
+		Long: `restore the objects saved by copy --backup
+
+Runs every <schema>__<type>__<name>.sql file of the backup directory on the
+database, tables first, then views and routines. Each file runs in one
+transaction, a failed file leaves its object as it was.`,
+		Example: `  dbtools restore -d reporting backups/20260119093000/reporting`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := RestoreConfig{}
+			if err := loadConfig(cmd, "restore", &config); err != nil {
+				return err
+			}
+			return runRestore(cmd, &config, args[0])
+		},
+	}
+	cmd.Flags().StringP("db", "d", "", "config host name or connection URI")
+	cmd.Flags().BoolP("dry-run", "n", false, "print the sql instead of running it")
+	return cmd
+}
+
+func runRestore(cmd *cobra.Command, config *RestoreConfig, dir string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	files, err := filepath.Glob(filepath.Join(dir, "*__*__*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no backup files in %s", dir)
+	}
+	slices.SortFunc(files, func(a, b string) int {
+		return cmp.Or(cmp.Compare(restoreRank(a), restoreRank(b)), strings.Compare(a, b))
+	})
+
+	if config.Debug {
+		for _, fn := range files {
+			data, err := os.ReadFile(fn)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "-- %s\n%s\n", filepath.Base(fn), data)
+		}
+		return nil
+	}
+
+	db, err := openHost(ctx, config.DBase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var errs []error
+	lines := [][]string{{"FILE", "STATUS", "ERROR"}}
+	for _, fn := range files {
+		name := filepath.Base(fn)
+		err := ctx.Err()
+		if err == nil {
+			var data []byte
+			data, err = os.ReadFile(fn)
+			if err == nil {
+				octx, cancel := timeoutContext(ctx)
+				_, _, err = db.ExecDDL(octx, strings.TrimSuffix(name, ".sql"), string(data))
+				cancel()
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			lines = append(lines, []string{name, "failed", err.Error()})
+			continue
+		}
+		lines = append(lines, []string{name, "ok", ""})
+	}
+	printColumns(out, lines, func(row, col int, v string) string {
+		switch {
+		case row == 0 || col != 1:
+			return v
+		case strings.TrimSpace(v) == "ok":
+			return okStyle.Render(v)
+		default:
+			return failStyle.Render(v)
+		}
+	})
+	return failed("objects", len(files), errs)
+}
+
+// restoreRank position of the object type of a backup file in restoreOrder
+func restoreRank(fn string) int {
+	parts := strings.SplitN(filepath.Base(fn), "__", 3)
+	if i := slices.Index(restoreOrder, parts[1]); i >= 0 {
+		return i
+	}
+	return len(restoreOrder)
+}
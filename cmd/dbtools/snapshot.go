@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ppreeper/dbtools/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// SnapshotConfig snapshot command settings
+type SnapshotConfig struct {
+	DBase  string `mapstructure:"db"`
+	Schema string `mapstructure:"schema"`
+	Output string `mapstructure:"output"`
+}
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "save the catalog of a database to a json file",
+		Long: `save the catalog of a database to a json file
+
+The snapshot holds the tables, columns, primary keys, indexes, views and
+routines of every schema and can be compared with dbtools diff.`,
+		Example: `  dbtools snapshot --db prod --schema public -o prod.json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config := SnapshotConfig{}
+			if err := loadConfig(cmd, "snapshot", &config); err != nil {
+				return err
+			}
+			if config.Output == "" {
+				return errors.New("no output file specified")
+			}
+			db, err := openHost(cmd.Context(), config.DBase)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			snap, err := snapshot.Take(cmd.Context(), db, hostLabel(config.DBase), config.Schema, global.Timeout)
+			if err != nil {
+				return err
+			}
+			if err := snap.Save(config.Output); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %d schemas saved to %s\n", hostLabel(config.DBase), len(snap.Schemas), config.Output)
+			return nil
+		},
+	}
+	cmd.Flags().StringP("db", "d", "", "config host name or connection URI")
+	cmd.Flags().String("schema", "", "only this schema")
+	cmd.Flags().StringP("output", "o", "", "snapshot file")
+	return cmd
+}
@@ -0,0 +1,69 @@
+// Package forward runs dbtools in place of the deprecated dbq and dbcopy
+// commands, translating their flags.
+package forward
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Args translates the flags set on fs into dbtools flags, names maps an old
+// flag name to the new one. Flags left at their default are not passed, so
+// the dbtools defaults and settings apply.
+func Args(fs *flag.FlagSet, names map[string]string) []string {
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		name, ok := names[f.Name]
+		if !ok {
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if f.Value.String() == "true" {
+				args = append(args, "--"+name)
+			} else {
+				args = append(args, "--"+name+"=false")
+			}
+			return
+		}
+		args = append(args, "--"+name+"="+f.Value.String())
+	})
+	return args
+}
+
+// Run prints a deprecation notice for the old command and runs dbtools with
+// args, exiting with its status. dbtools is looked up next to the running
+// binary, then in the PATH.
+func Run(old string, args []string) {
+	fmt.Fprintf(os.Stderr, "%s is deprecated and will be removed, use: dbtools %s\n", old, strings.Join(args, " "))
+	bin, err := dbtools()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", old, err)
+		os.Exit(1)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var ee *exec.ExitError
+	switch {
+	case errors.As(err, &ee):
+		os.Exit(ee.ExitCode())
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", old, err)
+		os.Exit(1)
+	}
+}
+
+// dbtools path of the dbtools binary
+func dbtools() (string, error) {
+	if exe, err := os.Executable(); err == nil {
+		if bin, err := exec.LookPath(filepath.Join(filepath.Dir(exe), "dbtools")); err == nil {
+			return bin, nil
+		}
+	}
+	return exec.LookPath("dbtools")
+}
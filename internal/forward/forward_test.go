@@ -0,0 +1,25 @@
+package forward
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+	fs := flag.NewFlagSet("dbcopy", flag.ContinueOnError)
+	fs.String("source", "", "")
+	fs.Bool("t", false, "")
+	fs.Bool("l", true, "")
+	fs.Int("j", 8, "")
+	fs.String("logfile", "dbcopy.log", "")
+	if err := fs.Parse([]string{"-source", "prod", "-t", "-l=false", "-j", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	got := Args(fs, map[string]string{"source": "source", "t": "tables", "l": "link", "j": "jobs", "logfile": "logfile"})
+	// flags left at their default are not passed
+	want := []string{"--jobs=4", "--link=false", "--source=prod", "--tables"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Args = %q, want %q", got, want)
+	}
+}
@@ -1,26 +1,519 @@
 package configfile
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
+	"github.com/ppreeper/dbtools/pkg/database"
 	"gopkg.in/yaml.v3"
 )
 
 type Host struct {
-	Hostname string `default:"localhost" json:"hostname"`
-	Port     int    `default:"8069" json:"port,omitempty"`
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port,omitempty"`
 	Driver   string `default:"pgx" json:"driver"`
-	Database string `default:"odoo" json:"database,omitempty"`
-	Username string `default:"odoo" json:"username"`
-	Password string `default:"odoo" json:"password"`
+	Database string `json:"database,omitempty"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// secret sources, read by Resolve when Password is empty
+	PasswordEnv  string `yaml:"password_env" json:"password_env,omitempty"`
+	PasswordCmd  string `yaml:"password_cmd" json:"password_cmd,omitempty"`
+	PasswordFile string `yaml:"password_file" json:"password_file,omitempty"`
+
+	TLS     TLS     `yaml:"tls" json:"tls,omitempty"`
+	Pool    Pool    `yaml:"pool" json:"pool,omitempty"`
+	Session Session `yaml:"session" json:"session,omitempty"`
+}
+
+// TLS connection encryption settings of a host
+type TLS = database.TLS
+
+// Pool connection pool limits of a host, zero keeps the database/sql default
+type Pool = database.Pool
+
+// Session settings applied to every connection of a host
+type Session = database.Session
+
+// driverDefaults values for unset host fields by driver
+var driverDefaults = map[string]Host{
+	"pgx":      {Port: 5432, Database: "postgres"},
+	"postgres": {Port: 5432, Database: "postgres"},
+	"mssql":    {Port: 1433, Database: "master"},
+}
+
+// Drivers returns the supported driver names
+func Drivers() []string {
+	dd := make([]string, 0, len(driverDefaults))
+	for d := range driverDefaults {
+		dd = append(dd, d)
+	}
+	slices.Sort(dd)
+	return dd
+}
+
+// ErrConfig matches every problem with the config file
+var ErrConfig = errors.New("config")
+
+// HostError problem with a host entry of the config file
+type HostError struct {
+	Host string
+	Line int
+	Err  error
+}
+
+func (e *HostError) Error() string {
+	if e.Host == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Host, e.Err)
+}
+
+func (e *HostError) Unwrap() error { return e.Err }
+
+func (e *HostError) Is(target error) bool { return target == ErrConfig }
+
+func errLine(err error) int {
+	var he *HostError
+	if errors.As(err, &he) {
+		return he.Line
+	}
+	return 0
+}
+
+// Config resolved hosts and groups of a config file
+type Config struct {
+	Hosts  map[string]Host
+	Groups map[string][]string
 }
 
-func GetConf(configFile string) map[string]Host {
+// GroupsKey top level key of the host groups, it cannot be a host name
+const GroupsKey = "groups"
+
+// GetConf reads the config file, printing any problems with it to stderr
+//
+// Deprecated: use Load, which returns the problems.
+func GetConf(configFile string) *Config {
+	c, err := Load(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return c
+}
+
+// Load reads and validates the config file, the valid hosts are returned
+// along with the problems of the others
+func Load(configFile string) (*Config, error) {
 	yamlFile, err := os.ReadFile(configFile)
-	ec.CheckErr(err)
-	data := make(map[string]Host)
-	err = yaml.Unmarshal(yamlFile, data)
-	ec.CheckErr(err)
-	return data
+	if err != nil {
+		return &Config{Hosts: map[string]Host{}, Groups: map[string][]string{}}, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	return Parse(yamlFile)
+}
+
+// Parse decodes config file data, expanding ${VAR} references in every
+// value, merging extends entries and applying the driver defaults. Every
+// invalid entry is reported as a *HostError and left out of the config.
+// Entries named with a leading dot are templates to extend only.
+func Parse(yamlFile []byte) (*Config, error) {
+	c := &Config{Hosts: map[string]Host{}, Groups: map[string][]string{}}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yamlFile, &doc); err != nil {
+		return c, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	if len(doc.Content) == 0 {
+		return c, nil
+	}
+	expandNode(&doc)
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return c, &HostError{Line: root.Line, Err: errors.New("expected a mapping of host names")}
+	}
+
+	var errs []error
+	p := &parser{entries: map[string]*yaml.Node{}, merged: map[string]*yaml.Node{}}
+	var names []string
+	var groups *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		k, v := root.Content[i], root.Content[i+1]
+		if k.Value == GroupsKey {
+			groups = v
+			continue
+		}
+		if first, ok := p.entries[k.Value]; ok {
+			errs = append(errs, &HostError{Host: k.Value, Line: k.Line, Err: fmt.Errorf("duplicate host, first defined on line %d", first.Line)})
+			p.duplicate = append(p.duplicate, k.Value)
+			continue
+		}
+		p.entries[k.Value] = v
+		names = append(names, k.Value)
+	}
+
+	for _, name := range names {
+		n, err := p.resolve(name, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if strings.HasPrefix(name, ".") || slices.Contains(p.duplicate, name) {
+			continue
+		}
+		h, herrs := parseHost(name, n)
+		if len(herrs) > 0 {
+			errs = append(errs, herrs...)
+			continue
+		}
+		c.Hosts[name] = h
+	}
+
+	if groups != nil {
+		errs = append(errs, c.parseGroups(groups, p.entries)...)
+	}
+	slices.SortStableFunc(errs, func(a, b error) int { return errLine(a) - errLine(b) })
+	return c, errors.Join(errs...)
+}
+
+// parser extends resolution state
+type parser struct {
+	entries   map[string]*yaml.Node
+	merged    map[string]*yaml.Node
+	duplicate []string
+}
+
+// resolve returns the entry merged over the entries it extends
+func (p *parser) resolve(name string, stack []string) (*yaml.Node, error) {
+	if n, ok := p.merged[name]; ok {
+		return n, nil
+	}
+	n := p.entries[name]
+	if n.Kind != yaml.MappingNode {
+		return n, nil
+	}
+	base, own := extendsOf(n)
+	if base == nil {
+		p.merged[name] = n
+		return n, nil
+	}
+	if slices.Contains(stack, base.Value) || base.Value == name {
+		return nil, &HostError{Host: name, Line: base.Line,
+			Err: fmt.Errorf("extends cycle %s", strings.Join(append(append(stack, name), base.Value), " -> "))}
+	}
+	if _, ok := p.entries[base.Value]; !ok {
+		return nil, &HostError{Host: name, Line: base.Line, Err: fmt.Errorf("extends unknown host %q", base.Value)}
+	}
+	parent, err := p.resolve(base.Value, append(stack, name))
+	if err != nil {
+		return nil, err
+	}
+	if parent.Kind != yaml.MappingNode {
+		return nil, &HostError{Host: name, Line: base.Line, Err: fmt.Errorf("extends %q which is not a host", base.Value)}
+	}
+	merged := mergeNode(parent, own)
+	p.merged[name] = merged
+	return merged, nil
+}
+
+// extendsOf returns the extends value and the mapping without it
+func extendsOf(n *yaml.Node) (*yaml.Node, *yaml.Node) {
+	var base *yaml.Node
+	own := *n
+	own.Content = nil
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "extends" {
+			base = n.Content[i+1]
+			continue
+		}
+		own.Content = append(own.Content, n.Content[i], n.Content[i+1])
+	}
+	return base, &own
+}
+
+// mergeNode returns mapping over merged onto base, nested mappings are
+// merged key by key
+func mergeNode(base, over *yaml.Node) *yaml.Node {
+	m := *over
+	m.Content = slices.Clone(base.Content)
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		k, v := over.Content[i], over.Content[i+1]
+		j := findKey(&m, k.Value)
+		switch {
+		case j < 0:
+			m.Content = append(m.Content, k, v)
+		case m.Content[j+1].Kind == yaml.MappingNode && v.Kind == yaml.MappingNode:
+			m.Content[j+1] = mergeNode(m.Content[j+1], v)
+		default:
+			m.Content[j+1] = v
+		}
+	}
+	return &m
+}
+
+// findKey returns the index of key in mapping m, -1 when missing
+func findKey(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseGroups reads the groups mapping of group names to host names or
+// glob patterns
+func (c *Config) parseGroups(n *yaml.Node, entries map[string]*yaml.Node) []error {
+	if n.Kind != yaml.MappingNode {
+		return []error{&HostError{Host: GroupsKey, Line: n.Line, Err: errors.New("expected a mapping of group names")}}
+	}
+	var errs []error
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if _, ok := entries[k.Value]; ok {
+			errs = append(errs, &HostError{Host: k.Value, Line: k.Line, Err: errors.New("group has the name of a host")})
+			continue
+		}
+		var members []string
+		if err := v.Decode(&members); err != nil {
+			errs = append(errs, &HostError{Host: k.Value, Line: v.Line, Err: errors.New("expected a list of hosts")})
+			continue
+		}
+		for _, m := range members {
+			if _, err := path.Match(m, ""); err != nil {
+				errs = append(errs, &HostError{Host: k.Value, Line: v.Line, Err: fmt.Errorf("bad pattern %q", m)})
+			} else if !isGlob(m) && entries[m] == nil {
+				errs = append(errs, &HostError{Host: k.Value, Line: v.Line, Err: fmt.Errorf("unknown host %q", m)})
+			}
+		}
+		c.Groups[k.Value] = members
+	}
+	return errs
+}
+
+// Names returns the sorted host names
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Hosts))
+	for name := range c.Hosts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Targets returns the host names of a host, group or glob pattern
+func (c *Config) Targets(spec string) ([]string, error) {
+	if _, ok := c.Hosts[spec]; ok {
+		return []string{spec}, nil
+	}
+	patterns, ok := c.Groups[spec]
+	if !ok {
+		if !isGlob(spec) {
+			return nil, fmt.Errorf("host or group %s not found", spec)
+		}
+		patterns = []string{spec}
+	}
+
+	var names []string
+	for _, p := range patterns {
+		if !isGlob(p) {
+			if _, ok := c.Hosts[p]; ok && !slices.Contains(names, p) {
+				names = append(names, p)
+			}
+			continue
+		}
+		for _, name := range c.Names() {
+			if ok, _ := path.Match(p, name); ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no hosts match %s", spec)
+	}
+	return names, nil
+}
+
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+func parseHost(name string, n *yaml.Node) (Host, []error) {
+	h := Host{}
+	if n.Kind != yaml.MappingNode {
+		return h, []error{&HostError{Host: name, Line: n.Line, Err: errors.New("expected a mapping of host settings")}}
+	}
+
+	errs := checkKeys(name, "", n, reflect.TypeOf(h))
+	if err := n.Decode(&h); err != nil {
+		errs = append(errs, &HostError{Host: name, Line: n.Line, Err: err})
+	}
+	if len(errs) > 0 {
+		return h, errs
+	}
+
+	applyDefaults(&h)
+	if h.Hostname == "" {
+		errs = append(errs, &HostError{Host: name, Line: n.Line, Err: errors.New("missing hostname")})
+	}
+	if _, ok := driverDefaults[h.Driver]; !ok {
+		errs = append(errs, &HostError{Host: name, Line: n.Line,
+			Err: fmt.Errorf("unknown driver %q, one of %s", h.Driver, strings.Join(Drivers(), ", "))})
+	}
+	if err := (database.Database{Driver: h.Driver, TLS: h.TLS, Pool: h.Pool, Session: h.Session}).CheckSettings(); err != nil {
+		errs = append(errs, &HostError{Host: name, Line: n.Line, Err: err})
+	}
+	return h, errs
+}
+
+// checkKeys reports the keys of mapping n that are not fields of struct t,
+// nested structs are checked against their mapping
+func checkKeys(name, prefix string, n *yaml.Node, t reflect.Type) []error {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		fields[key] = f.Type
+	}
+
+	var errs []error
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		ft, ok := fields[k.Value]
+		if !ok {
+			err := fmt.Errorf("unknown key %q", prefix+k.Value)
+			if prefix+k.Value == "host" {
+				err = fmt.Errorf("unknown key %q, did you mean hostname", k.Value)
+			}
+			errs = append(errs, &HostError{Host: name, Line: k.Line, Err: err})
+			continue
+		}
+		if ft.Kind() == reflect.Struct && v.Kind == yaml.MappingNode {
+			errs = append(errs, checkKeys(name, prefix+k.Value+".", v, ft)...)
+		}
+	}
+	return errs
+}
+
+// applyDefaults sets unset fields from their default tag, then from the
+// driver defaults
+func applyDefaults(h *Host) {
+	v := reflect.ValueOf(h).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		def, ok := t.Field(i).Tag.Lookup("default")
+		if !ok || !v.Field(i).IsZero() {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(def)
+		case reflect.Int:
+			if n, err := strconv.Atoi(def); err == nil {
+				f.SetInt(int64(n))
+			}
+		}
+	}
+
+	d := driverDefaults[h.Driver]
+	if h.Port == 0 {
+		h.Port = d.Port
+	}
+	if h.Database == "" {
+		h.Database = d.Database
+	}
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Expand replaces ${VAR} references with the environment value, unset
+// variables expand to an empty string
+func Expand(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
+
+func expandNode(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode {
+		if v := Expand(n.Value); v != n.Value {
+			// re-resolve the tag so port: ${PORT} decodes as an int
+			n.Value = v
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+	}
+	for _, c := range n.Content {
+		expandNode(c)
+	}
+}
+
+// Resolve returns the host with the password read from its secret source
+// and ~ expanded in file paths, only called when the host is opened so a
+// broken secret only affects that host
+func (h Host) Resolve() (Host, error) {
+	for _, fn := range []*string{&h.PasswordFile, &h.TLS.CA, &h.TLS.Cert, &h.TLS.Key} {
+		var err error
+		if *fn, err = expandHome(*fn); err != nil {
+			return h, err
+		}
+	}
+	if h.Password != "" {
+		return h, nil
+	}
+	switch {
+	case h.PasswordEnv != "":
+		pass, ok := os.LookupEnv(h.PasswordEnv)
+		if !ok {
+			return h, fmt.Errorf("password_env: %s is not set", h.PasswordEnv)
+		}
+		h.Password = pass
+	case h.PasswordFile != "":
+		data, err := os.ReadFile(h.PasswordFile)
+		if err != nil {
+			return h, fmt.Errorf("password_file: %w", err)
+		}
+		h.Password = firstLine(data)
+	case h.PasswordCmd != "":
+		var stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", h.PasswordCmd)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return h, fmt.Errorf("password_cmd: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		h.Password = firstLine(out)
+	}
+	return h, nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(fn string) (string, error) {
+	rest, ok := strings.CutPrefix(fn, "~/")
+	if !ok {
+		return fn, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fn, err
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// firstLine password secrets are the first line, pass and similar tools
+// print metadata after it
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimRight(line, "\r")
 }
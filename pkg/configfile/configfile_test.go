@@ -1,16 +1,19 @@
 package configfile
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
-
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
+	"time"
 )
 
 func TestGetConf(t *testing.T) {
 	t.Log("Logs are printed when a test fails")
 	userConfigDir, err := os.UserConfigDir()
-	ec.CheckErr(err)
+	if err != nil {
+		t.Skip(err)
+	}
 	c := GetConf(userConfigDir + "/dbtools/config.yml")
 	if err != nil {
 		t.Error("error loading config file")
@@ -31,3 +34,245 @@ func TestGetConf(t *testing.T) {
 // 		t.Log(fmt.Errorf("non_exist database config not found: %w", err))
 // 	}
 // }
+
+func TestParseExpand(t *testing.T) {
+	t.Setenv("DBT_HOST", "db1.example.com")
+	t.Setenv("DBT_PORT", "6432")
+	data := []byte(`prod:
+  driver: pgx
+  hostname: ${DBT_HOST}
+  port: ${DBT_PORT}
+  username: app_${DBT_UNSET}user
+  password: pa$$word
+`)
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := c.Hosts["prod"]
+	if h.Hostname != "db1.example.com" || h.Port != 6432 {
+		t.Errorf("got %s:%d, want db1.example.com:6432", h.Hostname, h.Port)
+	}
+	if h.Username != "app_user" {
+		t.Errorf("username %q, want app_user", h.Username)
+	}
+	if h.Password != "pa$$word" {
+		t.Errorf("password %q, unbraced $ must be kept", h.Password)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("DBT_PASS", "from-env")
+	fn := t.TempDir() + "/pass"
+	if err := os.WriteFile(fn, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host Host
+		want string
+	}{
+		{Host{Password: "plain", PasswordEnv: "DBT_PASS"}, "plain"},
+		{Host{PasswordEnv: "DBT_PASS"}, "from-env"},
+		{Host{PasswordFile: fn}, "from-file"},
+		{Host{PasswordCmd: "printf 'from-cmd\\nmeta: x\\n'"}, "from-cmd"},
+	}
+	for _, tt := range tests {
+		h, err := tt.host.Resolve()
+		if err != nil {
+			t.Errorf("%+v: %v", tt.host, err)
+			continue
+		}
+		if h.Password != tt.want {
+			t.Errorf("%+v: password %q, want %q", tt.host, h.Password, tt.want)
+		}
+	}
+
+	if _, err := (Host{PasswordEnv: "DBT_UNSET"}).Resolve(); err == nil {
+		t.Error("unset password_env: expected error")
+	}
+	if _, err := (Host{PasswordCmd: "exit 3"}).Resolve(); err == nil {
+		t.Error("failing password_cmd: expected error")
+	}
+}
+
+func TestParseValidate(t *testing.T) {
+	data := []byte(`pg:
+  hostname: db1
+mssql:
+  driver: mssql
+  hostname: db2
+  database: sales
+old:
+  host: db3
+nohost:
+  driver: pgx
+odbc:
+  driver: odbc
+  hostname: db4
+pg:
+  hostname: db5
+`)
+	c, err := Parse(data)
+	hosts := c.Hosts
+
+	want := map[string]Host{
+		"mssql": {Hostname: "db2", Port: 1433, Driver: "mssql", Database: "sales"},
+	}
+	if len(hosts) != len(want) || hosts["mssql"] != want["mssql"] {
+		t.Errorf("hosts %+v, want %+v", hosts, want)
+	}
+
+	var herrs []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var he *HostError
+		if !errors.As(e, &he) {
+			t.Fatalf("%v is not a HostError", e)
+		}
+		herrs = append(herrs, he.Host)
+	}
+	if got := strings.Join(herrs, ","); got != "old,nohost,odbc,pg" {
+		t.Errorf("errors for %s, want old,nohost,odbc,pg\n%v", got, err)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	c, err := Parse([]byte("pg:\n  hostname: db1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := c.Hosts
+	want := Host{Hostname: "db1", Port: 5432, Driver: "pgx", Database: "postgres"}
+	if hosts["pg"] != want {
+		t.Errorf("got %+v, want %+v", hosts["pg"], want)
+	}
+}
+
+func TestParseTLS(t *testing.T) {
+	data := []byte(`cloud:
+  hostname: db1
+  tls:
+    mode: verify-full
+    ca: /etc/ssl/rds.pem
+    server_name: db1.internal
+typo:
+  hostname: db2
+  tls:
+    mode: verify
+    sni: db2
+`)
+	c, err := Parse(data)
+	hosts := c.Hosts
+	want := TLS{Mode: "verify-full", CA: "/etc/ssl/rds.pem", ServerName: "db1.internal"}
+	if hosts["cloud"].TLS != want {
+		t.Errorf("tls %+v, want %+v", hosts["cloud"].TLS, want)
+	}
+	if _, ok := hosts["typo"]; ok {
+		t.Error("typo host should be invalid")
+	}
+	if err == nil || !strings.Contains(err.Error(), `unknown key "tls.sni"`) {
+		t.Errorf("expected unknown key tls.sni, got %v", err)
+	}
+}
+
+func TestParseSession(t *testing.T) {
+	data := []byte(`etl:
+  hostname: db1
+  pool:
+    max_open: 8
+    max_idle: 4
+    conn_max_lifetime: 30m
+  session:
+    application_name: nightly
+    statement_timeout: 5m
+    lock_timeout: 10s
+    search_path: app,public
+erp:
+  driver: mssql
+  hostname: db2
+  session:
+    search_path: dbo
+busy:
+  hostname: db3
+  pool:
+    max_open: 2
+    max_idle: 4
+`)
+	c, err := Parse(data)
+	h := c.Hosts["etl"]
+	if want := (Pool{MaxOpen: 8, MaxIdle: 4, ConnMaxLifetime: 30 * time.Minute}); h.Pool != want {
+		t.Errorf("pool %+v, want %+v", h.Pool, want)
+	}
+	want := Session{ApplicationName: "nightly", StatementTimeout: 5 * time.Minute, LockTimeout: 10 * time.Second, SearchPath: "app,public"}
+	if h.Session != want {
+		t.Errorf("session %+v, want %+v", h.Session, want)
+	}
+	for _, name := range []string{"erp", "busy"} {
+		if _, ok := c.Hosts[name]; ok {
+			t.Errorf("%s should be invalid", name)
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "mssql has no search_path") || !strings.Contains(err.Error(), "max_idle cannot exceed") {
+		t.Errorf("expected session and pool errors, got %v", err)
+	}
+}
+
+func TestParseExtends(t *testing.T) {
+	data := []byte(`.prod:
+  driver: mssql
+  hostname: sql1
+  username: app
+  tls:
+    mode: require
+t1:
+  extends: .prod
+  database: tenant1
+t2:
+  extends: t1
+  database: tenant2
+  tls:
+    server_name: sql1.internal
+loop1:
+  extends: loop2
+loop2:
+  extends: loop1
+orphan:
+  extends: nope
+groups:
+  tenants: [t1, t2]
+  all-t: ["t*"]
+  bad: [t9]
+`)
+	c, err := Parse(data)
+	if _, ok := c.Hosts[".prod"]; ok {
+		t.Error("templates are not hosts")
+	}
+	want := Host{Hostname: "sql1", Port: 1433, Driver: "mssql", Database: "tenant2", Username: "app",
+		TLS: TLS{Mode: "require", ServerName: "sql1.internal"}}
+	if c.Hosts["t2"] != want {
+		t.Errorf("t2 %+v, want %+v", c.Hosts["t2"], want)
+	}
+	if c.Hosts["t1"].Database != "tenant1" || c.Hosts["t1"].TLS.ServerName != "" {
+		t.Errorf("t1 %+v", c.Hosts["t1"])
+	}
+	for _, msg := range []string{"extends cycle", `extends unknown host "nope"`, `unknown host "t9"`} {
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error %q, got %v", msg, err)
+		}
+	}
+
+	for spec, want := range map[string]string{
+		"tenants": "t1,t2",
+		"all-t":   "t1,t2",
+		"t?":      "t1,t2",
+		"t1":      "t1",
+	} {
+		got, err := c.Targets(spec)
+		if err != nil || strings.Join(got, ",") != want {
+			t.Errorf("Targets(%s) = %v, %v, want %s", spec, got, err, want)
+		}
+	}
+	if _, err := c.Targets("x*"); err == nil {
+		t.Error("Targets(x*): expected error")
+	}
+}
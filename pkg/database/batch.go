@@ -0,0 +1,175 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//########
+// Batches
+//########
+
+// goLine a mssql batch separator line, GO with an optional repeat count
+var goLine = regexp.MustCompile(`(?i)^\s*GO(?:\s+(\d+))?\s*$`)
+
+// SplitBatches splits a sql script into the batches run one at a time on
+// driver: mssql scripts at GO lines, the others at semicolons outside of
+// strings, quoted identifiers, comments and dollar quoted bodies. Empty
+// batches are dropped, and comment only ones between semicolons.
+func SplitBatches(driver, script string) []string {
+	if driver == "mssql" {
+		return splitGo(script)
+	}
+	return splitSemicolons(script)
+}
+
+// JoinBatches joins batches into a script SplitBatches splits back into
+// them: mssql batches are ended by GO lines, the others by a semicolon
+func JoinBatches(driver string, batches []string) string {
+	var b strings.Builder
+	for _, q := range batches {
+		if q = strings.TrimSpace(q); q == "" {
+			continue
+		}
+		b.WriteString(q)
+		switch {
+		case driver == "mssql":
+			b.WriteString("\nGO")
+		case !strings.HasSuffix(q, ";"):
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// splitGo splits script at GO lines, GO n repeats the batch n times
+func splitGo(script string) []string {
+	var batches []string
+	var b strings.Builder
+	flush := func(count int) {
+		if q := strings.TrimSpace(b.String()); q != "" {
+			for range count {
+				batches = append(batches, q)
+			}
+		}
+		b.Reset()
+	}
+	for _, line := range strings.SplitAfter(script, "\n") {
+		m := goLine.FindStringSubmatch(line)
+		if m == nil {
+			b.WriteString(line)
+			continue
+		}
+		count := 1
+		if m[1] != "" {
+			count, _ = strconv.Atoi(m[1])
+		}
+		flush(count)
+	}
+	flush(1)
+	return batches
+}
+
+// splitSemicolons splits script at the semicolons ending its statements,
+// the semicolons are kept
+func splitSemicolons(script string) []string {
+	var batches []string
+	start, code := 0, false
+	flush := func(end int) {
+		if q := strings.TrimSpace(script[start:end]); code && q != "" {
+			batches = append(batches, q)
+		}
+		start, code = end, false
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if j := strings.IndexByte(script[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			// postgres block comments nest
+			depth := 1
+			for i += 2; i < len(script) && depth > 0; i++ {
+				switch {
+				case strings.HasPrefix(script[i:], "/*"):
+					depth++
+					i++
+				case strings.HasPrefix(script[i:], "*/"):
+					depth--
+					i++
+				}
+			}
+			i--
+		case c == '\'':
+			code = true
+			// E'...' strings escape with a backslash
+			escape := i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') && (i < 2 || !isIdentChar(script[i-2]))
+			for i++; i < len(script); i++ {
+				if escape && script[i] == '\\' {
+					i++
+				} else if script[i] == '\'' {
+					if i+1 < len(script) && script[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '"':
+			code = true
+			for i++; i < len(script); i++ {
+				if script[i] == '"' {
+					if i+1 < len(script) && script[i+1] == '"' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '$' && (i == 0 || !isIdentChar(script[i-1])):
+			code = true
+			if tag := dollarTag(script[i:]); tag != "" {
+				if j := strings.Index(script[i+len(tag):], tag); j >= 0 {
+					i += len(tag) + j + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			flush(i + 1)
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			code = true
+		}
+	}
+	flush(len(script))
+	return batches
+}
+
+// dollarTag returns the $tag$ s starts with, empty when there is none
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c >= '0' && c <= '9':
+			if i == 1 {
+				// $1 is a parameter
+				return ""
+			}
+		case !isIdentChar(c):
+			return ""
+		}
+	}
+	return ""
+}
+
+// isIdentChar reports whether c can be part of an unquoted identifier
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
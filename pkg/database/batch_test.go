@@ -0,0 +1,53 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitBatches(t *testing.T) {
+	tests := []struct {
+		driver, script string
+		want           []string
+	}{
+		{"postgres", "CREATE TABLE a (x int);\nINSERT INTO a VALUES (1);\n-- done\n",
+			[]string{"CREATE TABLE a (x int);", "INSERT INTO a VALUES (1);"}},
+		{"postgres", "SELECT 'a;b', \"c;d\", E'\\';';\nSELECT $1;",
+			[]string{"SELECT 'a;b', \"c;d\", E'\\';';", "SELECT $1;"}},
+		{"pgx", "CREATE FUNCTION f() RETURNS int LANGUAGE sql AS $body$ SELECT 1; $body$;\n/* a; /* b; */ */ SELECT 2",
+			[]string{"CREATE FUNCTION f() RETURNS int LANGUAGE sql AS $body$ SELECT 1; $body$;", "/* a; /* b; */ */ SELECT 2"}},
+		{"postgres", "DO $$ BEGIN RAISE NOTICE 'x;'; END $$;",
+			[]string{"DO $$ BEGIN RAISE NOTICE 'x;'; END $$;"}},
+		{"mssql", "CREATE TABLE a (x int);\nINSERT INTO a VALUES (1);\ngo\nCREATE VIEW v AS SELECT 1 AS x\nGO 2\n\nGO\n",
+			[]string{"CREATE TABLE a (x int);\nINSERT INTO a VALUES (1);", "CREATE VIEW v AS SELECT 1 AS x", "CREATE VIEW v AS SELECT 1 AS x"}},
+		{"mssql", "SELECT 1 AS going", []string{"SELECT 1 AS going"}},
+	}
+	for _, tt := range tests {
+		got := SplitBatches(tt.driver, tt.script)
+		if strings.Join(got, "\n|\n") != strings.Join(tt.want, "\n|\n") {
+			t.Errorf("%s %q\n got %q\nwant %q", tt.driver, tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestJoinBatches(t *testing.T) {
+	tests := []struct {
+		driver  string
+		batches []string
+		want    string
+	}{
+		{"postgres", []string{"CREATE TABLE a (x int);", " ", "GRANT SELECT ON a TO reader"},
+			"CREATE TABLE a (x int);\nGRANT SELECT ON a TO reader;\n"},
+		{"mssql", []string{"DROP PROCEDURE IF EXISTS p;", "CREATE PROCEDURE p AS\nBEGIN\nSELECT 1;\nEND;"},
+			"DROP PROCEDURE IF EXISTS p;\nGO\nCREATE PROCEDURE p AS\nBEGIN\nSELECT 1;\nEND;\nGO\n"},
+	}
+	for _, tt := range tests {
+		got := JoinBatches(tt.driver, tt.batches)
+		if got != tt.want {
+			t.Errorf("%s %q\n got %q\nwant %q", tt.driver, tt.batches, got, tt.want)
+		}
+		if back := SplitBatches(tt.driver, got); strings.Join(back, "\n|\n") != strings.Join(SplitBatches(tt.driver, tt.want), "\n|\n") {
+			t.Errorf("%s round trip %q", tt.driver, back)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package database
 import (
 	"context"
 	"fmt"
-	"time"
 )
 
 // PKey struct
@@ -12,9 +11,7 @@ type PKey struct {
 }
 
 // GetPKey func
-func (c *Conn) GetPKey(table string, timeout int) ([]PKey, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetPKey(ctx context.Context, table string) ([]PKey, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -57,10 +54,12 @@ func (c *Conn) GetPKey(table string, timeout int) ([]PKey, error) {
 		q += "\nAND C.TABLE_NAME IN (?)"
 		q += "\n)"
 		q += "\nORDER BY CLM.ORDINAL_POSITION"
+	default:
+		return nil, unsupported("get primary key", c.Source.Driver)
 	}
 	var pkey []PKey
 	if err := c.Source.SelectContext(ctx, &pkey, q, c.Source.Database, c.SSchema, table, c.Source.Database, c.SSchema, table); err != nil {
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, catalogErr("get primary key", objectName(c.SSchema, table), err)
 	}
 	return pkey, nil
 }
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/render"
+)
+
+//########
+// Data
+//########
+
+// ExportTable writes the rows of table to w as JSON lines, the first line
+// holds the column names. Values are kept as text and NULL as null, the
+// values of binary columns as {"base64": "..."}. mssql rowversion and
+// computed columns are left out, they cannot be inserted.
+func (db *Database) ExportTable(ctx context.Context, schema, table string, w io.Writer) (int64, error) {
+	object := objectName(schema, table)
+	skip, err := db.generatedColumns(ctx, schema, table)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM "%s"."%s"`, schema, table))
+	if err != nil {
+		return 0, catalogErr("export rows", object, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, catalogErr("export rows", object, err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, catalogErr("export rows", object, err)
+	}
+	vals := make([]sql.NullString, len(cols))
+	bins := make([][]byte, len(cols))
+	kinds := make([]columnKind, len(cols))
+	ptrs := make([]any, len(cols))
+	var names []string
+	for i, ct := range types {
+		ptrs[i] = &vals[i]
+		if kinds[i] = exportKind(ct.DatabaseTypeName()); kinds[i] != textColumn {
+			ptrs[i] = &bins[i]
+		}
+		if slices.Contains(skip, cols[i]) {
+			kinds[i] = skippedColumn
+			continue
+		}
+		names = append(names, cols[i])
+	}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(names); err != nil {
+		return 0, fmt.Errorf("export rows %s: %w", object, err)
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, catalogErr("export rows", object, err)
+		}
+		rec := make([]any, 0, len(names))
+		for i, k := range kinds {
+			if k != skippedColumn {
+				rec = append(rec, exportValue(k, vals[i], bins[i]))
+			}
+		}
+		if err := enc.Encode(rec); err != nil {
+			return n, fmt.Errorf("export rows %s: %w", object, err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, catalogErr("export rows", object, err)
+	}
+	return n, nil
+}
+
+// generatedColumns the columns of an mssql table the server fills in:
+// rowversion and computed columns
+func (db *Database) generatedColumns(ctx context.Context, schema, table string) ([]string, error) {
+	if db.Driver != "mssql" {
+		return nil, nil
+	}
+	var cols []string
+	q := `SELECT c.name FROM sys.columns c
+	WHERE c.object_id = OBJECT_ID(?) AND (c.system_type_id = 189 OR c.is_computed = 1)`
+	if err := db.SelectContext(ctx, &cols, q, fmt.Sprintf("[%s].[%s]", schema, table)); err != nil {
+		return nil, catalogErr("export rows", objectName(schema, table), err)
+	}
+	return cols, nil
+}
+
+// binaryValue value of a binary column in an export, base64 encoded
+type binaryValue struct {
+	Base64 []byte `json:"base64"`
+}
+
+// columnKind how the values of an exported column are written
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	binaryColumn
+	guidColumn // mssql UNIQUEIDENTIFIER, scanned as its 16 bytes
+	skippedColumn
+)
+
+// exportKind kind of a column of the database type
+func exportKind(name string) columnKind {
+	switch strings.ToUpper(name) {
+	case "BYTEA", "BINARY", "VARBINARY", "IMAGE", "BLOB":
+		return binaryColumn
+	case "UNIQUEIDENTIFIER":
+		return guidColumn
+	}
+	return textColumn
+}
+
+// exportValue JSON value of a scanned column, s holds text columns and b
+// the others
+func exportValue(kind columnKind, s sql.NullString, b []byte) any {
+	switch {
+	case kind == textColumn:
+		if s.Valid {
+			return s.String
+		}
+	case b == nil:
+	case kind == guidColumn && len(b) == 16:
+		return render.FormatGUID(b)
+	case kind == guidColumn:
+		return string(b)
+	default:
+		return binaryValue{b}
+	}
+	return nil
+}
+
+// ImportTable inserts the rows written by ExportTable into table in one
+// transaction, so a failed row leaves the table as it was
+func (db *Database) ImportTable(ctx context.Context, schema, table string, r io.Reader) (n int64, err error) {
+	object := objectName(schema, table)
+	importErr := func(err error) error {
+		return &Error{Kind: ErrDDL, Op: "import rows", Object: object, Err: err}
+	}
+	dec := json.NewDecoder(r)
+	var cols []string
+	if err := dec.Decode(&cols); err != nil {
+		return 0, importErr(fmt.Errorf("column names: %w", err))
+	}
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = `"` + c + `"`
+	}
+	q := db.Rebind(fmt.Sprintf(`INSERT INTO "%s"."%s" (%s) VALUES (%s)`, schema, table,
+		strings.Join(quoted, ","), strings.TrimSuffix(strings.Repeat("?,", len(cols)), ",")))
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, importErr(err)
+	}
+	defer tx.Rollback()
+	if db.Driver == "mssql" {
+		// explicit values for an identity column
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`IF OBJECTPROPERTY(OBJECT_ID('[%s].[%s]'), 'TableHasIdentity') = 1 SET IDENTITY_INSERT "%s"."%s" ON`, schema, table, schema, table)); err != nil {
+			return 0, importErr(err)
+		}
+	}
+	stmt, err := tx.PreparexContext(ctx, q)
+	if err != nil {
+		return 0, importErr(err)
+	}
+	defer stmt.Close()
+	for {
+		var rec []json.RawMessage
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return n, importErr(fmt.Errorf("row %d: %w", n+1, err))
+		}
+		if len(rec) != len(cols) {
+			return n, importErr(fmt.Errorf("row %d: %d values for %d columns", n+1, len(rec), len(cols)))
+		}
+		args := make([]any, len(rec))
+		for i, v := range rec {
+			if args[i], err = importValue(v); err != nil {
+				return n, importErr(fmt.Errorf("row %d: column %s: %w", n+1, cols[i], err))
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return n, importErr(fmt.Errorf("row %d: %w", n+1, err))
+		}
+		n++
+	}
+	if db.Driver == "mssql" {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`IF OBJECTPROPERTY(OBJECT_ID('[%s].[%s]'), 'TableHasIdentity') = 1 SET IDENTITY_INSERT "%s"."%s" OFF`, schema, table, schema, table)); err != nil {
+			return n, importErr(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return n, importErr(err)
+	}
+	return n, nil
+}
+
+// importValue argument of an exported value: nil, text or the bytes of a
+// binary value
+func importValue(v json.RawMessage) (any, error) {
+	switch {
+	case string(v) == "null":
+		return nil, nil
+	case len(v) > 0 && v[0] == '{':
+		var b binaryValue
+		if err := json.Unmarshal(v, &b); err != nil {
+			return nil, err
+		}
+		return b.Base64, nil
+	}
+	var s string
+	err := json.Unmarshal(v, &s)
+	return s, err
+}
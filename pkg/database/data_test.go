@@ -0,0 +1,117 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportImportTable(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDatabase(ctx, Database{Driver: "sqlite3", URI: t.TempDir() + "/data.db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, q := range []string{
+		`CREATE TABLE src (id INTEGER, name TEXT)`,
+		`CREATE TABLE dst (id INTEGER, name TEXT)`,
+		`INSERT INTO src VALUES (1, 'it''s'), (2, NULL)`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := db.ExportTable(ctx, "main", "src", &buf)
+	if err != nil || n != 2 {
+		t.Fatalf("export: %d rows, %v", n, err)
+	}
+	if want := "[\"id\",\"name\"]\n[\"1\",\"it's\"]\n[\"2\",null]\n"; buf.String() != want {
+		t.Errorf("export\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if n, err := db.ImportTable(ctx, "main", "dst", strings.NewReader(buf.String())); err != nil || n != 2 {
+		t.Fatalf("import: %d rows, %v", n, err)
+	}
+	var nulls int
+	if err := db.GetContext(ctx, &nulls, `SELECT count(*) FROM dst WHERE name IS NULL`); err != nil || nulls != 1 {
+		t.Errorf("null rows %d, %v", nulls, err)
+	}
+
+	// a bad row rolls the whole import back
+	if _, err := db.ImportTable(ctx, "main", "dst", strings.NewReader("[\"id\",\"name\"]\n[\"3\",\"x\"]\n[\"4\"]\n")); err == nil {
+		t.Error("short row: expected error")
+	}
+	var rows int
+	if err := db.GetContext(ctx, &rows, `SELECT count(*) FROM dst`); err != nil || rows != 2 {
+		t.Errorf("rows after failed import %d, %v", rows, err)
+	}
+}
+
+func TestExportImportBinary(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDatabase(ctx, Database{Driver: "sqlite3", URI: t.TempDir() + "/data.db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, q := range []string{
+		`CREATE TABLE src (id INTEGER, data BLOB)`,
+		`CREATE TABLE dst (id INTEGER, data BLOB)`,
+		`INSERT INTO src VALUES (1, x'00ff0a22'), (2, NULL)`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := db.ExportTable(ctx, "main", "src", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\"id\",\"data\"]\n[\"1\",{\"base64\":\"AP8KIg==\"}]\n[\"2\",null]\n"; buf.String() != want {
+		t.Errorf("export\n%s\nwant\n%s", buf.String(), want)
+	}
+	if _, err := db.ImportTable(ctx, "main", "dst", &buf); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	if err := db.GetContext(ctx, &data, `SELECT data FROM dst WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x00, 0xff, 0x0a, 0x22}; !bytes.Equal(data, want) {
+		t.Errorf("imported %x, want %x", data, want)
+	}
+	var typ string
+	if err := db.GetContext(ctx, &typ, `SELECT typeof(data) FROM dst WHERE id = 1`); err != nil || typ != "blob" {
+		t.Errorf("imported as %s, %v, want blob", typ, err)
+	}
+}
+
+func TestExportValue(t *testing.T) {
+	// mssql sends 6F9619FF-8B86-D011-B42D-00C04FC964FF with the first three
+	// groups little-endian
+	guid := []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff}
+	for _, c := range []struct {
+		name string
+		kind columnKind
+		s    sql.NullString
+		b    []byte
+		want any
+	}{
+		{"text", textColumn, sql.NullString{String: "x", Valid: true}, nil, "x"},
+		{"null text", textColumn, sql.NullString{}, nil, nil},
+		{"guid", exportKind("UNIQUEIDENTIFIER"), sql.NullString{}, guid, "6f9619ff-8b86-d011-b42d-00c04fc964ff"},
+		{"null guid", guidColumn, sql.NullString{}, nil, nil},
+		{"binary", exportKind("varbinary"), sql.NullString{}, []byte{0, 1}, binaryValue{[]byte{0, 1}}},
+	} {
+		if got := exportValue(c.kind, c.s, c.b); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: exportValue = %#v, want %#v", c.name, got, c.want)
+		}
+	}
+}
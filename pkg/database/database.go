@@ -1,14 +1,23 @@
 package database
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	_ "github.com/denisenkom/go-mssqldb" // mssql driver
-	_ "github.com/jackc/pgx/v5/stdlib"
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
 )
 
 //########
@@ -17,58 +26,218 @@ import (
 
 // Database struct contains sql pointer
 type Database struct {
-	Name     string `json:"name,omitempty"`
-	Hostname string `json:"hostname,omitempty"`
-	Port     int    `json:"port,omitempty"`
-	Driver   string `json:"driver,omitempty"`
-	Database string `json:"database,omitempty"`
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
-	URI      string `json:"uri,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Hostname string  `json:"hostname,omitempty"`
+	Port     int     `json:"port,omitempty"`
+	Driver   string  `json:"driver,omitempty"`
+	Database string  `json:"database,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Password string  `json:"password,omitempty"`
+	URI      string  `json:"uri,omitempty"`
+	TLS      TLS     `json:"tls,omitempty"`
+	Pool     Pool    `json:"pool,omitempty"`
+	Session  Session `json:"session,omitempty"`
 	*sqlx.DB
 }
 
 // Conn struct
-// type Conn struct {
-// 	Source  *Database
-// 	Dest    *Database
-// 	SSchema string
-// 	DSchema string
-// }
-
-// OpenDatabase open database
-func OpenDatabase(db Database) (*Database, error) {
-	var err error
+type Conn struct {
+	Source    *Database
+	Dest      *Database
+	SSchema   string
+	DSchema   string
+	Templates *Templates // ddl templates, nil for the built-in ones
+	Mapping   *Mapping   // source to destination renames, nil for none
+}
+
+// DestTable destination name of a source table
+func (c *Conn) DestTable(table string) string {
+	return c.Mapping.Table(table)
+}
+
+// cancelHandler cancels a running query on the server. A connection that is
+// still starting up has no backend to cancel, it is closed at once.
+type cancelHandler struct {
+	*pgconn.CancelRequestContextWatcherHandler
+	connect    *pgconn.DeadlineContextWatcherHandler
+	connecting bool
+}
+
+func (h *cancelHandler) HandleCancel(ctx context.Context) {
+	h.connecting = h.Conn.PID() == 0
+	if h.connecting {
+		h.connect.HandleCancel(ctx)
+		return
+	}
+	h.CancelRequestContextWatcherHandler.HandleCancel(ctx)
+}
+
+func (h *cancelHandler) HandleUnwatchAfterCancel() {
+	if h.connecting {
+		h.connect.HandleUnwatchAfterCancel()
+		return
+	}
+	h.CancelRequestContextWatcherHandler.HandleUnwatchAfterCancel()
+}
+
+// CheckSettings reports invalid TLS, pool and session settings and the ones
+// the driver cannot honor
+func (db Database) CheckSettings() error {
+	return errors.Join(db.TLS.check(db.Driver), db.Pool.check(), db.Session.check(db.Driver))
+}
+
+// OpenDatabase open database, ctx bounds the connection check
+func OpenDatabase(ctx context.Context, db Database) (*Database, error) {
+	if err := db.CheckSettings(); err != nil {
+		return nil, &Error{Kind: ErrUnsupported, Op: "open", Err: err}
+	}
 	db.GetURI()
-	db.DB, err = sqlx.Open(db.Driver, db.URI)
-	ec.FatalErr(err, "cannot open database")
-	if err = db.Ping(); err != nil {
-		ec.FatalErr(err, "cannot ping database")
+	switch db.Driver {
+	case "postgres", "pgx":
+		// opened from the parsed config for the TLS server name override
+		cfg, err := pgx.ParseConfig(db.URI)
+		if err != nil {
+			return nil, connErr("open", db.host(), err)
+		}
+		if sn := db.TLS.ServerName; sn != "" {
+			if cfg.TLSConfig != nil {
+				cfg.TLSConfig.ServerName = sn
+			}
+			for _, fb := range cfg.Fallbacks {
+				if fb.TLSConfig != nil {
+					fb.TLSConfig.ServerName = sn
+				}
+			}
+		}
+		db.Session.pgParams(cfg)
+		// a canceled context cancels the running query on the server
+		cfg.BuildContextWatcherHandler = func(c *pgconn.PgConn) ctxwatch.Handler {
+			return &cancelHandler{
+				CancelRequestContextWatcherHandler: &pgconn.CancelRequestContextWatcherHandler{Conn: c, DeadlineDelay: 5 * time.Second},
+				connect:                            &pgconn.DeadlineContextWatcherHandler{Conn: c.Conn()},
+			}
+		}
+		db.DB = sqlx.NewDb(stdlib.OpenDB(*cfg), "pgx")
+	case "mssql":
+		// opened from a connector to run the session settings on connect
+		conn, err := mssql.NewConnector(db.URI)
+		if err != nil {
+			return nil, connErr("open", db.host(), err)
+		}
+		conn.SessionInitSQL = db.Session.mssqlInitSQL()
+		db.DB = sqlx.NewDb(sql.OpenDB(conn), "mssql")
+	case "sqlite3":
+		// the pure go driver registers as sqlite, sqlx binds sqlite3 the same
+		if !slices.Contains(sql.Drivers(), "sqlite") {
+			return nil, &Error{Kind: ErrUnsupported, Op: "open", Err: errors.New("sqlite support is not built in, build with -tags sqlite")}
+		}
+		sdb, err := sql.Open("sqlite", db.URI)
+		if err != nil {
+			return nil, connErr("open", db.host(), err)
+		}
+		db.DB = sqlx.NewDb(sdb, "sqlite3")
+	default:
+		var err error
+		db.DB, err = sqlx.Open(db.Driver, db.URI)
+		if err != nil {
+			return nil, connErr("open", db.host(), err)
+		}
 	}
-	return &db, err
+	db.Pool.apply(&db)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, connErr("ping", db.host(), err)
+	}
+	return &db, nil
+}
+
+// host the server name used in errors, the file of sqlite databases
+func (db *Database) host() string {
+	return cmp.Or(db.Hostname, db.Database)
 }
 
-// GenURI generate db uri string
+// GenURI generate db uri string, a URI set by ParseURI is kept
 func (db *Database) GetURI() {
+	if db.URI != "" {
+		return
+	}
 	if db.Driver == "postgres" || db.Driver == "pgx" {
 		port := 5432
 		if db.Port != 0 {
 			port = db.Port
 		}
-		db.URI = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", db.Username, db.Password, db.Hostname, port, db.Database)
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(db.Username, db.Password),
+			Host:     db.Hostname + ":" + strconv.Itoa(port),
+			Path:     "/" + db.Database,
+			RawQuery: db.TLS.pgParams().Encode(),
+		}
+		db.URI = u.String()
 	}
 	if db.Driver == "mssql" {
-		db.URI = fmt.Sprintf("server=%s;user id=%s;password=%s;database=%s;encrypt=disable;connection timeout=7200;keepAlive=30", db.Hostname, db.Username, db.Password, db.Database)
+		port := 1433
+		if db.Port != 0 {
+			port = db.Port
+		}
+		db.URI = fmt.Sprintf("server=%s;port=%d;user id=%s;password=%s;database=%s;%s;%s;keepAlive=30", db.Hostname, port, db.Username, db.Password, db.Database, db.TLS.mssqlParams(), db.Session.mssqlParams())
 	}
 }
 
 // ExecProcedure executes stored procedure
-func (db *Database) ExecProcedure(q string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func (db *Database) ExecProcedure(ctx context.Context, q string) error {
 	fmt.Println(q)
-	_, err := db.ExecContext(ctx, q)
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return ddlErr("", err)
+	}
+	return nil
+}
+
+// ExecDDL executes the ddl statements of object in one transaction, so a
+// failed statement or a canceled ctx leaves the object as it was. Empty
+// statements are skipped. It returns the number of statements run and the
+// rows they affected.
+func (db *Database) ExecDDL(ctx context.Context, object string, stmts ...string) (n int, rows int64, err error) {
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
-		panic(err)
+		return 0, 0, ddlErr(object, err)
+	}
+	defer tx.Rollback()
+	for _, q := range stmts {
+		if strings.TrimSpace(q) == "" {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, q)
+		if err != nil {
+			return n, rows, ddlErr(object, err)
+		}
+		n++
+		if r, err := res.RowsAffected(); err == nil && r > 0 {
+			rows += r
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return n, rows, ddlErr(object, err)
+	}
+	return n, rows, nil
+}
+
+// ExecEach executes the ddl statements of object one at a time outside of a
+// transaction, for statements that cannot run in one. A failed statement
+// leaves the ones before it applied.
+func (db *Database) ExecEach(ctx context.Context, object string, stmts ...string) (n int, rows int64, err error) {
+	for _, q := range stmts {
+		if strings.TrimSpace(q) == "" {
+			continue
+		}
+		res, err := db.ExecContext(ctx, q)
+		if err != nil {
+			return n, rows, ddlErr(object, err)
+		}
+		n++
+		if r, err := res.RowsAffected(); err == nil && r > 0 {
+			rows += r
+		}
 	}
+	return n, rows, nil
 }
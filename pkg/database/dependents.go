@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+//########
+// Dependents
+//########
+
+// Dependent object that depends on a table or view
+type Dependent struct {
+	Schema string `db:"schema_name" json:"schema"`
+	Name   string `db:"object_name" json:"name"`
+	Kind   string `db:"object_kind" json:"kind"` // view, materialized view or foreign key
+}
+
+// ObjectExists reports whether a table, view, index or routine called name
+// exists in schema
+func (db *Database) ObjectExists(ctx context.Context, schema, name string) (bool, error) {
+	q := ""
+	var args []any
+	switch db.Driver {
+	case "postgres", "pgx":
+		q = `SELECT (SELECT count(*) FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2)
+		+ (SELECT count(*) FROM pg_catalog.pg_proc p
+			JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2)`
+		args = []any{schema, name}
+	case "mssql":
+		q = `SELECT (SELECT count(*) FROM sys.objects
+			WHERE schema_id = SCHEMA_ID(?) AND name = ?)
+		+ (SELECT count(*) FROM sys.indexes i
+			JOIN sys.objects o ON o.object_id = i.object_id
+			WHERE o.schema_id = SCHEMA_ID(?) AND i.name = ?)`
+		args = []any{schema, name, schema, name}
+	default:
+		return false, unsupported("find object", db.Driver)
+	}
+	var n int
+	if err := db.GetContext(ctx, &n, q, args...); err != nil {
+		return false, catalogErr("find object", objectName(schema, name), err)
+	}
+	return n > 0, nil
+}
+
+// GetDependents returns the objects dropping the table or view takes with
+// it, the views (recursively) and foreign keys DROP ... CASCADE drops on
+// postgres. mssql has no CASCADE, there the same dependents break or block
+// the drop.
+func (db *Database) GetDependents(ctx context.Context, schema, name string) ([]Dependent, error) {
+	q := ""
+	var args []any
+	switch db.Driver {
+	case "postgres", "pgx":
+		q = `WITH RECURSIVE deps AS (
+			SELECT c.oid FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2
+			UNION
+			SELECT r.ev_class FROM deps d
+			JOIN pg_catalog.pg_depend dep ON dep.refobjid = d.oid
+			AND dep.classid = 'pg_catalog.pg_rewrite'::regclass AND dep.deptype = 'n'
+			JOIN pg_catalog.pg_rewrite r ON r.oid = dep.objid
+			WHERE r.ev_class <> d.oid
+		)
+		SELECT n.nspname AS schema_name, c.relname AS object_name
+		,CASE c.relkind WHEN 'm' THEN 'materialized view' ELSE 'view' END AS object_kind
+		FROM deps d
+		JOIN pg_catalog.pg_class c ON c.oid = d.oid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE NOT (n.nspname = $1 AND c.relname = $2)
+		UNION ALL
+		SELECT n.nspname, c.relname || '.' || con.conname, 'foreign key'
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'f' AND con.confrelid IN (
+			SELECT c.oid FROM pg_catalog.pg_class c
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2)
+		ORDER BY 1, 2`
+		args = []any{schema, name}
+	case "mssql":
+		object := fmt.Sprintf("[%s].[%s]", schema, name)
+		// views on views are followed, 32 is the view nesting limit
+		q = `WITH deps AS (
+			SELECT d.referencing_id AS object_id, 1 AS depth
+			FROM sys.sql_expression_dependencies d
+			WHERE d.referenced_id = OBJECT_ID(?) AND d.referencing_id <> d.referenced_id
+			UNION ALL
+			SELECT d.referencing_id, x.depth + 1
+			FROM deps x
+			JOIN sys.objects o ON o.object_id = x.object_id AND o.type = 'V'
+			JOIN sys.sql_expression_dependencies d ON d.referenced_id = x.object_id
+			WHERE d.referencing_id <> x.object_id AND x.depth < 32
+		)
+		SELECT OBJECT_SCHEMA_NAME(o.object_id) AS schema_name
+		,o.name AS object_name
+		,CASE o.type WHEN 'V' THEN 'view' ELSE LOWER(o.type_desc) END AS object_kind
+		FROM (SELECT DISTINCT object_id FROM deps) x
+		JOIN sys.objects o ON o.object_id = x.object_id
+		WHERE o.object_id <> OBJECT_ID(?)
+		UNION ALL
+		SELECT OBJECT_SCHEMA_NAME(f.parent_object_id)
+		,OBJECT_NAME(f.parent_object_id) + '.' + f.name, 'foreign key'
+		FROM sys.foreign_keys f
+		WHERE f.referenced_object_id = OBJECT_ID(?)
+		ORDER BY 1, 2`
+		args = []any{object, object, object}
+	default:
+		return nil, unsupported("list dependents", db.Driver)
+	}
+	dd := []Dependent{}
+	if err := db.SelectContext(ctx, &dd, q, args...); err != nil {
+		return nil, catalogErr("list dependents", objectName(schema, name), err)
+	}
+	return dd, nil
+}
+
+// GetRowEstimate returns the row count of table estimated from the
+// statistics, without scanning it
+func (db *Database) GetRowEstimate(ctx context.Context, schema, table string) (int64, error) {
+	q := ""
+	var args []any
+	switch db.Driver {
+	case "postgres", "pgx":
+		q = `SELECT COALESCE(SUM(GREATEST(c.reltuples, 0)), 0)::bigint
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2`
+		args = []any{schema, table}
+	case "mssql":
+		q = `SELECT COALESCE(SUM(p.rows), 0)
+		FROM sys.partitions p
+		WHERE p.object_id = OBJECT_ID(?) AND p.index_id IN (0, 1)`
+		args = []any{fmt.Sprintf("[%s].[%s]", schema, table)}
+	default:
+		return 0, unsupported("estimate rows", db.Driver)
+	}
+	var n int64
+	if err := db.GetContext(ctx, &n, q, args...); err != nil {
+		return 0, catalogErr("estimate rows", objectName(schema, table), err)
+	}
+	return n, nil
+}
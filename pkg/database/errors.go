@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+//########
+// Errors
+//########
+
+// Error kinds, match them with errors.Is
+var (
+	ErrConnection  = errors.New("connection")
+	ErrCatalog     = errors.New("catalog query")
+	ErrDDL         = errors.New("ddl execution")
+	ErrUnsupported = errors.New("unsupported")
+)
+
+// Error database error with the operation and object it concerns
+type Error struct {
+	Kind   error  // ErrConnection, ErrCatalog, ErrDDL or ErrUnsupported
+	Op     string // what was done, e.g. "list tables"
+	Object string // schema.object, empty when not object specific
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error() + ": " + e.Op
+	if e.Object != "" {
+		msg += " " + e.Object
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// connErr connection error to host
+func connErr(op, host string, err error) error {
+	return &Error{Kind: ErrConnection, Op: op, Object: host, Err: err}
+}
+
+// catalogErr catalog query error on object
+func catalogErr(op, object string, err error) error {
+	return &Error{Kind: ErrCatalog, Op: op, Object: object, Err: err}
+}
+
+// ddlErr ddl statement error on object
+func ddlErr(object string, err error) error {
+	return &Error{Kind: ErrDDL, Op: "exec", Object: object, Err: err}
+}
+
+// unsupported driver error
+func unsupported(op, driver string) error {
+	return &Error{Kind: ErrUnsupported, Op: op, Err: fmt.Errorf("driver %q", driver)}
+}
+
+// objectName schema qualified object name
+func objectName(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	err := catalogErr("get columns", objectName("sales", "orders"), io.EOF)
+	if got, want := err.Error(), "catalog query: get columns sales.orders: EOF"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrCatalog) || !errors.Is(err, io.EOF) || errors.Is(err, ErrDDL) {
+		t.Errorf("%v: wrong kind", err)
+	}
+	var dbErr *Error
+	if !errors.As(err, &dbErr) || dbErr.Object != "sales.orders" {
+		t.Errorf("%v: object not kept", err)
+	}
+
+	c := Conn{Source: &Database{Driver: "sqlite3"}, Dest: &Database{Driver: "sqlite3"}}
+	if _, err := c.GetTables(context.Background(), "main", "BASE TABLE"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("sqlite3 tables: got %v, want unsupported", err)
+	}
+	if _, _, err := c.GetUpdateTableSchema(context.Background(), "t"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("sqlite3 update: got %v, want unsupported", err)
+	}
+	if _, err := c.Dest.GetDependents(context.Background(), "main", "t"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("sqlite3 dependents: got %v, want unsupported", err)
+	}
+}
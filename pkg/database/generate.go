@@ -1,336 +1,155 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"strings"
-
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
 )
 
 //########
 // Generate
 //########
 
-// GenTable generate table creation
-func (c *Conn) GenTables(table string, cols []Column, pkey []PKey) (sqld, sqlc string) {
-	clen := len(cols)
-	plen := len(pkey)
-	switch c.Dest.Driver {
-	case "postgres", "pgx":
-		sqld += fmt.Sprintf("\nDROP TABLE IF EXISTS \"%s\".\"%s\" CASCADE;", c.DSchema, table)
-		sqlc += fmt.Sprintf("\nCREATE TABLE IF NOT EXISTS \"%s\".\"%s\" (\n", c.DSchema, table)
-		for k, c := range cols {
-			cdefault := ""
-			if c.ColumnDefault != "" {
-				cdefault += " DEFAULT " + strings.ReplaceAll(c.ColumnDefault, "getdate()", "CURRENT_TIMESTAMP")
-			}
-			sqlc += fmt.Sprintf("\"%s\" %s %s%s", c.ColumnName, c.DataType, c.IsNullable, cdefault)
-			if k == clen-1 {
-				if plen > 0 {
-					sqlc += ",\n"
-					sqlc += "PRIMARY KEY ("
-					for v, p := range pkey {
-						if v == plen-1 {
-							sqlc += "\"" + p.PKey + "\""
-						} else {
-							sqlc += "\"" + p.PKey + "\","
-						}
-					}
-					sqlc += ")" + "\n"
-				} else {
-					sqlc += "\n"
-				}
-			} else {
-				sqlc += ",\n"
-			}
-		}
-		sqlc += ");\n"
-	case "mssql":
-		sqld += fmt.Sprintf("\nDROP TABLE \"%s\".\"%s\";", c.DSchema, table)
-		sqlc += fmt.Sprintf("\nCREATE TABLE \"%s\".\"%s\" (\n", c.DSchema, table)
-		for k, c := range cols {
-			if k == clen-1 {
-				if plen > 0 {
-					sqlc += fmt.Sprintf("%s,\n", c.ColumnName)
-					sqlc += "PRIMARY KEY ("
-					for v, p := range pkey {
-						if v == plen-1 {
-							sqlc += fmt.Sprintf("\"%s\"", p.PKey)
-						} else {
-							sqlc += fmt.Sprintf("\"%s\",", p.PKey)
-						}
-					}
-					sqlc += ")\n"
-				} else {
-					// q += c.Column + "\n"
-					sqlc += fmt.Sprintf("%s\n", c.ColumnName)
-				}
-			} else {
-				sqlc += fmt.Sprintf("%s,\n", c.ColumnName)
-			}
-		}
-		sqlc += ")\n"
-	default:
-		sqld = ""
-		sqlc = ""
-	}
-	return
+// GenTables generate table creation
+func (c *Conn) GenTables(table string, cols []Column, pkey []PKey) (sqld, sqlc string, err error) {
+	return c.render("table", c.model(table, cols, pkey))
 }
 
 // GenTableIndexSQL generate table index sql
-func (c *Conn) GenTableIndexSQL(tableName string) (sqld, sqlc string) {
-	idxs, err := c.GetTableIndexSchema(tableName)
-	ec.CheckErr(err)
+func (c *Conn) GenTableIndexSQL(ctx context.Context, tableName string) (sqld, sqlc string, err error) {
+	idxs, err := c.GetTableIndexSchema(ctx, tableName)
+	if err != nil {
+		return "", "", err
+	}
 	for _, i := range idxs {
-		idx := "\"" + strings.Replace(strings.Replace(i.Table+`_`+i.Columns+"_idx", "\"", "", -1), ",", "_", -1) + "\""
-		exists := ""
-		notexists := ""
-		switch c.Dest.Driver {
-		case "postgres", "pgx":
-			exists = "IF EXISTS "
-			notexists = "IF NOT EXISTS "
-		default:
-			exists = ""
-			notexists = ""
-		}
-		sqld += `DROP INDEX ` + exists + `"` + c.DSchema + `".` + idx + `;` + "\n"
-		sqlc += `CREATE INDEX ` + notexists + `` + idx + ` ON "` + c.DSchema + `"."` + i.Table + `" (` + i.Columns + `);` + "\n"
+		_, dsql, csql := c.GenIndex(i)
+		sqld += dsql + "\n"
+		sqlc += csql + "\n"
 	}
 	return
 }
 
-// GenLink generate table creation
-func (c *Conn) GenLink(table string, cols []Column, pkey []PKey) (sqld, sqlc string) {
-	tmp := ""
-	if table == strings.ToUpper(table) {
-		tmp = "TEMP"
-	} else {
-		tmp = "temp"
-	}
-	clen := len(cols)
+// GenIndex generate a source index on the destination, named after its
+// renamed table and columns
+func (c *Conn) GenIndex(i Index) (idx, sqld, sqlc string) {
+	table := c.Mapping.Table(i.Table)
+	cols := c.Mapping.indexColumns(i.Table, i.Columns)
+	idx = IndexName(table, cols)
+	notexists := ""
 	if c.Dest.Driver == "postgres" || c.Dest.Driver == "pgx" {
-		sqld += fmt.Sprintf("\nDROP FOREIGN TABLE IF EXISTS \"%s\".\"%s%s\" CASCADE;\n", c.DSchema, table, tmp)
-		sqlc += fmt.Sprintf("CREATE FOREIGN TABLE IF NOT EXISTS \"%s\".\"%s%s\" (\n", c.DSchema, table, tmp)
-		for k, c := range cols {
-			if k == clen-1 {
-				sqlc += fmt.Sprintf("%s\n", c.ColumnName)
-			} else {
-				sqlc += fmt.Sprintf("%s,\n", c.ColumnName)
-			}
+		notexists = "IF NOT EXISTS "
+	}
+	sqld = DropIndexSQL(c.Dest.Driver, c.DSchema, table, idx)
+	sqlc = `CREATE INDEX ` + notexists + idx + ` ON "` + c.DSchema + `"."` + table + `" (` + cols + `);`
+	return idx, sqld, sqlc
+}
+
+// GenTableSwap generate a table replacement that never leaves the table
+// missing: stage builds the table and its indexes under a staging name, swap
+// drops the table and renames the staging table to it
+func (c *Conn) GenTableSwap(table string, cols []Column, pkey []PKey, idxs []Index) (stage, swap string, err error) {
+	m := c.model(table, cols, pkey)
+	table = m.Table
+	staging := stagingName(table)
+	m.Table, m.Indexes = staging, idxs
+	switch c.Dest.Driver {
+	case "postgres", "pgx":
+		// index and constraint names are unique in the schema, the staging
+		// ones are renamed once the old table is gone
+		m.PKName = staging + "_pkey"
+		sqld, sqlc, err := c.render("table", m)
+		if err != nil {
+			return "", "", err
 		}
-		sqlc += ")\n"
-		sqlc += fmt.Sprintf("SERVER %s \nOPTIONS (", c.Source.Name)
-		sqlc += fmt.Sprintf("table_name '%s.%s', ", c.SSchema, table)
-		sqlc += "row_estimate_method 'showplan_all', "
-		sqlc += "match_column_names '0');\n"
-	} else if c.Dest.Driver == "mssql" {
-		sqld += fmt.Sprintf("\nDROP VIEW \"%s\".\"%s%s\";\n", c.DSchema, table, tmp)
-		sqlc += fmt.Sprintf("CREATE VIEW \"%s\".\"%s%s\" AS\nSELECT\n", c.DSchema, table, tmp)
-		for k, c := range cols {
-			collation := ""
-			if c.DataType == "CHAR" ||
-				c.DataType == "VARCHAR" ||
-				c.DataType == "NCHAR" ||
-				c.DataType == "NVARCHAR" {
-				collation = "COLLATE database_default "
-			}
-			if k == clen-1 {
-				sqlc += fmt.Sprintf("\"%s\" %s\"%s\"\n", c.ColumnName, collation, c.ColumnName)
-			} else {
-				sqlc += fmt.Sprintf("\"%s\" %s\"%s\",\n", c.ColumnName, collation, c.ColumnName)
-			}
+		stage = sqld + sqlc
+		swap = DropTableSQL(c.Dest.Driver, c.DSchema, table)
+		swap += fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" RENAME TO \"%s\";\n", c.DSchema, staging, table)
+		if len(pkey) > 0 {
+			swap += fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" RENAME CONSTRAINT \"%s_pkey\" TO \"%s_pkey\";\n", c.DSchema, table, staging, table)
 		}
-		sqlc += fmt.Sprintf("FROM \"%s\".\"%s\".\"%s\".\"%s\";\n", c.Source.Hostname, c.Source.Database, c.SSchema, table)
+		for _, i := range idxs {
+			cols := c.Mapping.indexColumns(i.Table, i.Columns)
+			sidx := IndexName(staging, cols)
+			stage += `CREATE INDEX ` + sidx + ` ON "` + c.DSchema + `"."` + staging + `" (` + cols + `);` + "\n"
+			swap += `ALTER INDEX "` + c.DSchema + `".` + sidx + ` RENAME TO ` + IndexName(table, cols) + `;` + "\n"
+		}
+	case "mssql":
+		// index names are unique per table, the staging table gets the final
+		// ones
+		sqld, sqlc, err := c.render("table", m)
+		if err != nil {
+			return "", "", err
+		}
+		stage = sqld + sqlc
+		for _, i := range idxs {
+			cols := c.Mapping.indexColumns(i.Table, i.Columns)
+			stage += `CREATE INDEX ` + IndexName(table, cols) + ` ON "` + c.DSchema + `"."` + staging + `" (` + cols + `);` + "\n"
+		}
+		swap = DropTableSQL(c.Dest.Driver, c.DSchema, table)
+		swap += fmt.Sprintf("EXEC sp_rename '[%s].[%s]', '%s';\n", c.DSchema, staging, table)
 	}
-	return sqld, sqlc
+	return
 }
 
-// GenUpdate generate update procedure
-func (c *Conn) GenUpdate(table string, cols []Column, pkey []PKey) (sqld, sqlc string) {
-	columns := trimCols(cols, pkey)
+// stagingName name table is built under before it replaces the old one
+func stagingName(table string) string {
+	return table + "__new"
+}
 
-	sqld, sqlc = tableUpdProcStart(c.Dest.Driver, c.DSchema, table)
-	sqlc += tableDeleteSQL(c.Dest.Driver, c.DSchema, table, pkey, cols)
-	if len(pkey) != len(cols) {
-		sqlc += tableUpdateSQL(c.Dest.Driver, c.DSchema, table, pkey, columns)
-	}
-	sqlc += tableInsertSQL(c.Dest.Driver, c.DSchema, table, pkey, cols)
-	sqlc += tableUpdProcEnd(c.Dest.Driver, table)
-	return
+// IndexName generated quoted name of the index on columns of table
+func IndexName(table, columns string) string {
+	return "\"" + strings.Replace(strings.Replace(table+`_`+columns+"_idx", "\"", "", -1), ",", "_", -1) + "\""
 }
 
-func tableUpdProcStart(destDriver, schema, tableName string) (sqld, sqlc string) {
-	tmp := ""
-	if tableName == strings.ToUpper(tableName) {
-		tmp = "TEMP"
-	} else {
-		tmp = "temp"
+// DropIndexSQL drop index statement, mssql names the table of the index
+func DropIndexSQL(driver, schema, table, idx string) string {
+	if driver == "mssql" {
+		return `DROP INDEX IF EXISTS ` + idx + ` ON "` + schema + `"."` + table + `";`
 	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqld += fmt.Sprintf("\nDROP PROCEDURE IF EXISTS \"%s\".\"upd_%s\"();", schema, tableName)
-		sqlc += fmt.Sprintf("\nCREATE OR REPLACE PROCEDURE \"%s\".\"upd_%s\"()\nLANGUAGE plpgsql\nAS $procedure$\nBEGIN\n", schema, tableName)
-	} else if destDriver == "mssql" {
-		sqld += fmt.Sprintf("\nDROP PROCEDURE \"%s\".\"upd_%s\";", schema, tableName)
-		sqlc += fmt.Sprintf("\nCREATE PROCEDURE \"%s\".\"upd_%s\" AS\nBEGIN\n", schema, tableName)
-		sqlc += fmt.Sprintf("IF OBJECT_ID('tempdb..#%s','U') IS NOT NULL DROP TABLE tempdb.#%s\n", tableName, tableName)
-		sqlc += fmt.Sprintf("SELECT * INTO #%s FROM \"%s\".\"%s%s\"\n", tableName, schema, tableName, tmp)
-	}
-	return sqld, sqlc
+	return `DROP INDEX IF EXISTS "` + schema + `".` + idx + `;`
 }
 
-func tableUpdProcEnd(destDriver, tableName string) (sqlc string) {
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += "END\n$procedure$;\n"
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("IF OBJECT_ID('tempdb..#%s','U') IS NOT NULL DROP TABLE tempdb.#%s\n", tableName, tableName)
-		sqlc += "END;\n"
+// LinkName name of the link reading the source of a destination table,
+// <table>temp or TEMP for an upper case table name
+func LinkName(table string) string {
+	if table == strings.ToUpper(table) {
+		return table + "TEMP"
 	}
-	return sqlc
+	return table + "temp"
 }
 
-func tableDeleteSQL(destDriver, schema, tableName string, pkey []PKey, allColumns []Column) (sqlc string) {
-	ttemp := "temp"
-	if schema == "ep1" {
-		ttemp = "TEMP"
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += "DELETE\n"
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("DELETE \"%s\".\"%s\"\n", schema, tableName)
-	}
-	sqlc += fmt.Sprintf("FROM \"%s\".\"%s\"\n", schema, tableName)
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += fmt.Sprintf("USING \"%s\".\"%s\" AS d\n", schema, tableName)
-		sqlc += fmt.Sprintf("LEFT OUTER JOIN \"%s\".\"%s%s\" \"%s%s\" ON", schema, tableName, ttemp, tableName, ttemp)
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("LEFT JOIN #%s \"%s%s\" ON", tableName, ttemp, tableName)
-	}
-	plen := len(pkey)
-	for k, p := range pkey {
-		if destDriver == "postgres" || destDriver == "pgx" {
-			sqlc += fmt.Sprintf("\nd.\"%s\" = \"%s%s\".\"%s\"", p.PKey, tableName, ttemp, p.PKey)
-		} else if destDriver == "mssql" {
-			sqlc += fmt.Sprintf("\n\"%s\".\"%s\" = \"%s%s\".\"%s\"", tableName, p.PKey, tableName, ttemp, p.PKey)
-		}
-		if k == plen-1 {
-			sqlc += "\n"
-		} else {
-			sqlc += " AND "
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += "WHERE"
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		for k, p := range pkey {
-			sqlc += fmt.Sprintf("\n\"%s\".\"%s\" = d.\"%s\" ", tableName, p.PKey, p.PKey)
-			if k == plen-1 {
-				sqlc += "\n"
-			} else {
-				sqlc += " AND "
-			}
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += fmt.Sprintf("AND \"%s%s\".\"%s\" IS NULL;\n", tableName, ttemp, allColumns[0].ColumnName)
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("WHERE \"%s%s\".\"%s\" IS NULL\n", tableName, ttemp, allColumns[0].ColumnName)
-	}
-	return sqlc
+// UpdateName name of the procedure syncing a destination table with its
+// link
+func UpdateName(table string) string {
+	return "upd_" + table
 }
 
-func tableUpdateSQL(destDriver, schema, tableName string, pkey []PKey, columns []Column) (sqlc string) {
-	ttemp := "temp"
-	if schema == "ep1" {
-		ttemp = "TEMP"
+// DropTableSQL drop table statement, postgres drops the dependent views too
+func DropTableSQL(driver, schema, table string) string {
+	if driver == "mssql" {
+		return fmt.Sprintf("DROP TABLE IF EXISTS \"%s\".\"%s\";\n", schema, table)
 	}
+	return fmt.Sprintf("DROP TABLE IF EXISTS \"%s\".\"%s\" CASCADE;\n", schema, table)
+}
 
-	sqlc += fmt.Sprintf("UPDATE \"%s\".\"%s\"\nSET", schema, tableName)
-	plen := len(pkey)
-	clen := len(columns)
-	for k, c := range columns {
-		sqlc += fmt.Sprintf("\n\"%s\" = \"%s%s\".\"%s\"", c.ColumnName, tableName, ttemp, c.ColumnName)
-		if k == clen-1 {
-			sqlc += ""
-		} else {
-			sqlc += ","
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += fmt.Sprintf("\nFROM \"%s\".\"%s%s\" \"%s%s\"", schema, tableName, ttemp, tableName, ttemp)
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("\nFROM #%s \"%s%s\"", tableName, ttemp, tableName)
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += "\nWHERE"
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("\nJOIN \"%s\".\"%s\" ON", schema, tableName)
-	}
-	for k, p := range pkey {
-		sqlc += fmt.Sprintf("\n\"%s\".\"%s\" = \"%s%s\".\"%s\"", tableName, p.PKey, tableName, ttemp, p.PKey)
-		if k == plen-1 {
-			sqlc += "\n"
-		} else {
-			sqlc += " AND "
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += "AND ("
-	} else if destDriver == "mssql" {
-		sqlc += "WHERE ("
-	}
-	for k, c := range columns {
-		sqlc += fmt.Sprintf("\n\"%s\".\"%s\" <> \"%s%s\".\"%s\"", tableName, c.ColumnName, tableName, ttemp, c.ColumnName)
-		if k == clen-1 {
-			sqlc += "\n"
-		} else {
-			sqlc += " OR "
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += ");\n"
-	} else if destDriver == "mssql" {
-		sqlc += ")\n"
-	}
-	return sqlc
+// MoveTableSQL moves table with its data and indexes from one schema to
+// another, creating the schema when needed
+func MoveTableSQL(driver, from, to, table string) string {
+	if driver == "mssql" {
+		return fmt.Sprintf("IF SCHEMA_ID('%s') IS NULL EXEC('CREATE SCHEMA \"%s\"');\n", to, to) +
+			fmt.Sprintf("ALTER SCHEMA \"%s\" TRANSFER \"%s\".\"%s\";\n", to, from, table)
+	}
+	return fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS \"%s\";\n", to) +
+		fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" SET SCHEMA \"%s\";\n", from, table, to)
 }
 
-func tableInsertSQL(destDriver, schema, tableName string, pkey []PKey, allColumns []Column) (sqlc string) {
-	ttemp := "temp"
-	if schema == "ep1" {
-		ttemp = "TEMP"
-	}
+// GenLink generate a link to the source table: a foreign table on postgres,
+// a view over a linked server on mssql
+func (c *Conn) GenLink(table string, cols []Column, pkey []PKey) (sqld, sqlc string, err error) {
+	return c.render("link", c.model(table, cols, pkey))
+}
 
-	plen := len(pkey)
-	clen := len(allColumns)
-	sqlc += fmt.Sprintf("INSERT INTO \"%s\".\"%s\"\n", schema, tableName)
-	sqlc += "SELECT"
-	for k, c := range allColumns {
-		sqlc += fmt.Sprintf("\n\"%s%s\".\"%s\" \"%s\"", tableName, ttemp, c.ColumnName, c.ColumnName)
-		if k == clen-1 {
-			sqlc += "\n"
-		} else {
-			sqlc += ","
-		}
-	}
-	sqlc += fmt.Sprintf("FROM \"%s\".\"%s\"\n", schema, tableName)
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += fmt.Sprintf("RIGHT JOIN \"%s\".\"%s%s\" \"%s%s\" ON", schema, tableName, ttemp, tableName, ttemp)
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("RIGHT JOIN #%s \"%s%s\" ON", tableName, tableName, ttemp)
-	}
-	for k, p := range pkey {
-		sqlc += fmt.Sprintf("\n\"%s\".\"%s\" = \"%s%s\".\"%s\"", tableName, p.PKey, tableName, ttemp, p.PKey)
-		if k == plen-1 {
-			sqlc += "\n"
-		} else {
-			sqlc += " AND "
-		}
-	}
-	if destDriver == "postgres" || destDriver == "pgx" {
-		sqlc += fmt.Sprintf("WHERE \"%s\".\"%s\" IS NULL;\n", tableName, allColumns[0].ColumnName)
-	} else if destDriver == "mssql" {
-		sqlc += fmt.Sprintf("WHERE \"%s\".\"%s\" IS NULL\n", tableName, allColumns[0].ColumnName)
-	}
-	return sqlc
+// GenUpdate generate update procedure
+func (c *Conn) GenUpdate(table string, cols []Column, pkey []PKey) (sqld, sqlc string, err error) {
+	return c.render("update", c.model(table, cols, pkey))
 }
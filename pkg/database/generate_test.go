@@ -0,0 +1,194 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenTableSwap(t *testing.T) {
+	cols := []Column{{ColumnName: "id", DataType: "integer", IsNullable: "NOT NULL"}, {ColumnName: "name", DataType: "text"}}
+	pkey := []PKey{{PKey: "id"}}
+	idxs := []Index{{Table: "orders", Columns: `"name"`}}
+
+	c := Conn{Dest: &Database{Driver: "pgx"}, DSchema: "sales"}
+	stage, swap, err := c.GenTableSwap("orders", cols, pkey, idxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`DROP TABLE IF EXISTS "sales"."orders__new" CASCADE;`,
+		`CREATE TABLE IF NOT EXISTS "sales"."orders__new" (`,
+		`CONSTRAINT "orders__new_pkey" PRIMARY KEY ("id")`,
+		`CREATE INDEX "orders__new_name_idx" ON "sales"."orders__new" ("name");`,
+	} {
+		if !strings.Contains(stage, want) {
+			t.Errorf("pgx stage missing %s\n%s", want, stage)
+		}
+	}
+	wantSwap := `DROP TABLE IF EXISTS "sales"."orders" CASCADE;
+ALTER TABLE "sales"."orders__new" RENAME TO "orders";
+ALTER TABLE "sales"."orders" RENAME CONSTRAINT "orders__new_pkey" TO "orders_pkey";
+ALTER INDEX "sales"."orders__new_name_idx" RENAME TO "orders_name_idx";
+`
+	if swap != wantSwap {
+		t.Errorf("pgx swap:\n got %s\nwant %s", swap, wantSwap)
+	}
+
+	c.Dest.Driver = "mssql"
+	stage, swap, err = c.GenTableSwap("orders", cols, pkey, idxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `CREATE INDEX "orders_name_idx" ON "sales"."orders__new" ("name");`; !strings.Contains(stage, want) {
+		t.Errorf("mssql stage missing %s\n%s", want, stage)
+	}
+	wantSwap = `DROP TABLE IF EXISTS "sales"."orders";
+EXEC sp_rename '[sales].[orders__new]', 'orders';
+`
+	if swap != wantSwap {
+		t.Errorf("mssql swap:\n got %s\nwant %s", swap, wantSwap)
+	}
+}
+
+func TestGenTablesMSSQLDefaults(t *testing.T) {
+	cols := []Column{{ColumnName: "id", DataType: "INT", IsNullable: "NOT NULL", ColumnDefault: "nextval('orders_id_seq'::regclass)"}}
+	c := Conn{Source: &Database{Driver: "pgx"}, Dest: &Database{Driver: "mssql"}, DSchema: "sales"}
+	_, sqlc, err := c.GenTables("orders", cols, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"id\" INT NOT NULL\n)"; !strings.Contains(sqlc, want) {
+		t.Errorf("postgres default kept on mssql, want %s:\n%s", want, sqlc)
+	}
+}
+
+func TestObjectNames(t *testing.T) {
+	cols := []Column{{ColumnName: "id", DataType: "integer"}}
+	pkey := []PKey{{PKey: "id"}}
+	for _, table := range []string{"orders", "ORDERS"} {
+		for _, driver := range []string{"pgx", "mssql"} {
+			c := Conn{Source: &Database{Driver: "mssql"}, Dest: &Database{Driver: driver}, SSchema: "dbo", DSchema: "sales"}
+			_, sqlc, err := c.GenLink(table, cols, pkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `"sales"."` + LinkName(table) + `"`; !strings.Contains(sqlc, want) {
+				t.Errorf("%s link missing %s\n%s", driver, want, sqlc)
+			}
+			_, sqlc, err = c.GenUpdate(table, cols, pkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `"sales"."` + UpdateName(table) + `"`; !strings.Contains(sqlc, want) {
+				t.Errorf("%s update missing %s\n%s", driver, want, sqlc)
+			}
+		}
+	}
+}
+
+// genCase input of the generated sql golden files
+type genCase struct {
+	schema, table string
+	cols          []Column
+	pkey          []PKey
+}
+
+func genCases() map[string]genCase {
+	cols := []Column{
+		{ColumnName: "id", DataType: "integer", IsNullable: "NOT NULL"},
+		{ColumnName: "name", DataType: "VARCHAR", ColumnDefault: "'x'"},
+		{ColumnName: "created", DataType: "timestamp", IsNullable: "NOT NULL", ColumnDefault: "getdate()"},
+	}
+	return map[string]genCase{
+		"pkey":      {"sales", "orders", cols, []PKey{{PKey: "id"}}},
+		"composite": {"ep1", "ORDERS", cols, []PKey{{PKey: "id"}, {PKey: "name"}}},
+		"nokey":     {"sales", "log", cols, nil},
+		"allkey":    {"sales", "pairs", cols[:2], []PKey{{PKey: "id"}, {PKey: "name"}}},
+		"nocols":    {"sales", "gone", nil, []PKey{{PKey: "id"}}},
+	}
+}
+
+// genAll output of every generator for tc on driver
+func genAll(driver string, tc genCase) string {
+	c := &Conn{
+		Source:  &Database{Name: "prod", Hostname: "srv", Database: "erp", Driver: "mssql"},
+		Dest:    &Database{Driver: driver},
+		SSchema: "dbo",
+		DSchema: tc.schema,
+	}
+	out := ""
+	add := func(what, sqld, sqlc string, err error) {
+		if err != nil {
+			sqlc += "\nerror: " + err.Error()
+		}
+		out += "-- " + what + " drop\n" + sqld + "\n-- " + what + " create\n" + sqlc + "\n"
+	}
+	sqld, sqlc, err := c.GenTables(tc.table, tc.cols, tc.pkey)
+	add("GenTables", sqld, sqlc, err)
+	m := c.model(tc.table, tc.cols, tc.pkey)
+	m.PKName = tc.table + "_pkey"
+	sqld, sqlc, err = c.render("table", m)
+	add("genTable pkName", sqld, sqlc, err)
+	sqld, sqlc, err = c.GenLink(tc.table, tc.cols, tc.pkey)
+	add("GenLink", sqld, sqlc, err)
+	if len(tc.cols) > 0 {
+		sqld, sqlc, err = c.GenUpdate(tc.table, tc.cols, tc.pkey)
+		add("GenUpdate", sqld, sqlc, err)
+	}
+	return out
+}
+
+func TestGenerateGolden(t *testing.T) {
+	for name, tc := range genCases() {
+		for _, driver := range []string{"pgx", "mssql"} {
+			fn := filepath.Join("testdata", "generate", name+"."+driver+".sql")
+			want, err := os.ReadFile(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := genAll(driver, tc); got != string(want) {
+				t.Errorf("%s:\n got %s\nwant %s", fn, got, want)
+			}
+		}
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "after"}}GRANT SELECT ON "{{.Schema}}"."{{.Table}}" TO reader;
+{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "table.postgres.tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Conn{Dest: &Database{Driver: "pgx"}, DSchema: "sales", Templates: tmpl}
+	cols := []Column{{ColumnName: "id", DataType: "integer"}}
+	sqld, sqlc, err := c.GenTables("orders", cols, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `DROP TABLE IF EXISTS "sales"."orders" CASCADE;`; !strings.Contains(sqld, want) {
+		t.Errorf("drop should stay the built-in one, got %s", sqld)
+	}
+	if want := `GRANT SELECT ON "sales"."orders" TO reader;`; !strings.Contains(sqlc, want) {
+		t.Errorf("create missing %s\n%s", want, sqlc)
+	}
+
+	// the override does not leak into the built-in templates
+	c.Templates = nil
+	if _, sqlc, _ := c.GenTables("orders", cols, nil); strings.Contains(sqlc, "GRANT") {
+		t.Errorf("built-in create changed:\n%s", sqlc)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "view.postgres.tmpl"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("unknown template file should fail")
+	}
+}
@@ -3,7 +3,6 @@ package database
 import (
 	"context"
 	"fmt"
-	"time"
 )
 
 //########
@@ -24,17 +23,17 @@ type IndexList struct {
 }
 
 // GetIndexes returns list of Indexes and definitions
-func (c *Conn) GetIndexes(schema string, timeout int) ([]IndexList, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetIndexes(ctx context.Context, schema string) ([]IndexList, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
 		q += `select p.indexname from pg_catalog.pg_indexes p
 		left join (
-			SELECT CONSTRAINT_NAME`
+			SELECT CONSTRAINT_NAME
+			`
 		q += fmt.Sprintf("FROM %s.INFORMATION_SCHEMA.TABLE_CONSTRAINTS", c.Source.Database)
-		q += `where CONSTRAINT_TYPE = 'PRIMARY KEY'
+		q += `
+			where CONSTRAINT_TYPE = 'PRIMARY KEY'
 		) c on p.indexname = c.constraint_name
 		where p.schemaname not in ('information_schema','pg_catalog')
 		and c.constraint_name is null
@@ -56,16 +55,18 @@ func (c *Conn) GetIndexes(schema string, timeout int) ([]IndexList, error) {
 		and index_id > 0 and i.is_primary_key <> 1
 		and schema_name(t.schema_id) = ?
 		order by schema_name(t.schema_id) + '.' + t."name", i.index_id`
+	default:
+		return nil, unsupported("list indexes", c.Source.Driver)
 	}
 	vv := []IndexList{}
 	if err := c.Source.SelectContext(ctx, &vv, q, schema); err != nil {
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, catalogErr("list indexes", schema, err)
 	}
 	return vv, nil
 }
 
 // GetIndexeschema returns Indexes and definition
-func (c *Conn) GetIndexSchema(schema, index string) (Index, error) {
+func (c *Conn) GetIndexSchema(ctx context.Context, schema, index string) (Index, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -74,14 +75,16 @@ func (c *Conn) GetIndexSchema(schema, index string) (Index, error) {
 		,p.indexdef
 		from pg_catalog.pg_indexes p
 		left join (
-			SELECT CONSTRAINT_NAME`
+			SELECT CONSTRAINT_NAME
+			`
 		q += fmt.Sprintf("FROM %s.INFORMATION_SCHEMA.TABLE_CONSTRAINTS", c.Source.Database)
-		q += `) c on p.indexname = c.constraint_name
+		q += `
+		) c on p.indexname = c.constraint_name
 		where p.schemaname not in ('information_schema','pg_catalog')
 		and c.constraint_name is null
 		and p.schemaname = $1 and p.indexname = $2
 		order by schemaname,tablename`
-	case "mysql":
+	case "mssql":
 		q += `select schema_name(t.schema_id) "schemaname"
 		,t."name" "tablename"
 		,i."name" "indexname"
@@ -115,16 +118,18 @@ func (c *Conn) GetIndexSchema(schema, index string) (Index, error) {
 		and schema_name(t.schema_id) = ?
 		and i."name" = ?
 		order by schema_name(t.schema_id) + '.' + t."name", i.index_id`
+	default:
+		return Index{}, unsupported("get index", c.Source.Driver)
 	}
 	vv := Index{}
-	if err := c.Source.Get(&vv, q, schema, index); err != nil {
-		return Index{}, fmt.Errorf("select: %w", err)
+	if err := c.Source.GetContext(ctx, &vv, q, schema, index); err != nil {
+		return Index{}, catalogErr("get index", objectName(schema, index), err)
 	}
 	return vv, nil
 }
 
 // GetIndexeschema returns Indexes and definition
-func (c *Conn) GetTableIndexSchema(table string) ([]Index, error) {
+func (c *Conn) GetTableIndexSchema(ctx context.Context, table string) ([]Index, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -173,17 +178,20 @@ func (c *Conn) GetTableIndexSchema(table string) ([]Index, error) {
 		q += `and index_id > 0 and i.is_primary_key <> 1` + "\n"
 		q += `and schema_name(t.schema_id) = ? and t."name" = ?` + "\n"
 		q += `order by schema_name(t.schema_id) + '.' + t."name", i.index_id`
+	default:
+		return nil, unsupported("get table indexes", c.Source.Driver)
 	}
 	vv := []Index{}
-	if err := c.Source.Select(&vv, q, c.SSchema, table); err != nil {
-		return []Index{}, fmt.Errorf("select: %w", err)
+	if err := c.Source.SelectContext(ctx, &vv, q, c.SSchema, table); err != nil {
+		return nil, catalogErr("get table indexes", objectName(c.SSchema, table), err)
 	}
 	return vv, nil
 }
 
-func (db *Database) GetTableIndexSchema(schema, table string) ([]Index, error) {
+func (db *Database) GetTableIndexSchema(ctx context.Context, schema, table string) ([]Index, error) {
 	q := ""
-	if db.Driver == "postgres" || db.Driver == "pgx" {
+	switch db.Driver {
+	case "postgres", "pgx":
 		q += "select p.schemaname,p.tablename,p.indexname" + "\n"
 		q += `,'"'||replace(replace(split_part(split_part(p.indexdef,'(',2),')',1),'"',''),',','","')||'"' as indexcolumns` + "\n"
 		q += `,p.indexdef` + "\n"
@@ -197,7 +205,7 @@ func (db *Database) GetTableIndexSchema(schema, table string) ([]Index, error) {
 		q += `and p.schemaname = '` + schema + `'` + "\n"
 		q += `and p.tablename = '` + table + `'` + "\n"
 		q += `order by schemaname,tablename,indexname`
-	} else if db.Driver == "mssql" {
+	case "mssql":
 		q += `select schema_name(t.schema_id) "schemaname"` + "\n"
 		q += `,t."name" "tablename"` + "\n"
 		q += `,i."name" "indexname"` + "\n"
@@ -231,11 +239,13 @@ func (db *Database) GetTableIndexSchema(schema, table string) ([]Index, error) {
 		q += `and schema_name(t.schema_id) = '` + schema + `'` + "\n"
 		q += `and t."name" = '` + table + `'` + "\n"
 		q += `order by schema_name(t.schema_id) + '.' + t."name", i.index_id`
+	default:
+		return nil, unsupported("get table indexes", db.Driver)
 	}
 	// fmt.Println(q)
 	vv := []Index{}
-	if err := db.Select(&vv, q); err != nil {
-		return []Index{}, fmt.Errorf("select: %w", err)
+	if err := db.SelectContext(ctx, &vv, q); err != nil {
+		return nil, catalogErr("get table indexes", objectName(schema, table), err)
 	}
 	return vv, nil
 }
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+//########
+// Rename
+//########
+
+// Mapping rules turning source identifiers into destination ones, a nil
+// Mapping keeps the names
+type Mapping struct {
+	Schemas map[string]string `yaml:"schemas"` // source schema: destination schema
+	Tables  NameRules         `yaml:"tables"`
+	Columns NameRules         `yaml:"columns"` // names keys are column or table.column
+}
+
+// NameRules rules renaming one kind of identifier. An explicit name wins,
+// otherwise the prefix and suffix are stripped, the case changed and the
+// new prefix and suffix added, in that order.
+type NameRules struct {
+	Case        string            `yaml:"case"` // lower, upper or snake
+	StripPrefix string            `yaml:"strip_prefix"`
+	StripSuffix string            `yaml:"strip_suffix"`
+	AddPrefix   string            `yaml:"add_prefix"`
+	AddSuffix   string            `yaml:"add_suffix"`
+	Names       map[string]string `yaml:"names"`
+}
+
+// LoadMapping reads the rename rules of a YAML file
+func LoadMapping(fn string) (*Mapping, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("rename rules: %w", err)
+	}
+	m := &Mapping{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("rename rules %s: %w", fn, err)
+	}
+	if err := m.Check(); err != nil {
+		return nil, fmt.Errorf("rename rules %s: %w", fn, err)
+	}
+	return m, nil
+}
+
+// Check reports rules that cannot be applied, and explicit names giving
+// two source names the same destination name
+func (m *Mapping) Check() error {
+	if m == nil {
+		return nil
+	}
+	for what, r := range map[string]NameRules{"tables": m.Tables, "columns": m.Columns} {
+		switch r.Case {
+		case "", "lower", "upper", "snake":
+		default:
+			return fmt.Errorf("%s: unknown case %q, expected lower, upper or snake", what, r.Case)
+		}
+	}
+	if err := collision("schemas", slices.Sorted(maps.Keys(m.Schemas)), m.Schema); err != nil {
+		return err
+	}
+	if err := collision("tables", slices.Sorted(maps.Keys(m.Tables.Names)), m.Table); err != nil {
+		return err
+	}
+	// column names collide within their table, unqualified ones in any
+	scopes := make(map[string][]string)
+	for _, k := range slices.Sorted(maps.Keys(m.Columns.Names)) {
+		table, _, ok := strings.Cut(k, ".")
+		if !ok {
+			table = ""
+		}
+		scopes[table] = append(scopes[table], k)
+	}
+	for _, keys := range scopes {
+		if err := collision("columns", keys, func(k string) string { return m.Columns.Names[k] }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckSchemas reports two source schemas becoming the same destination
+// schema
+func (m *Mapping) CheckSchemas(schemas []string) error {
+	return collision("schemas", schemas, m.Schema)
+}
+
+// CheckTables reports two source tables becoming the same destination table
+func (m *Mapping) CheckTables(tables []string) error {
+	return collision("tables", tables, m.Table)
+}
+
+// collision reports two names dest gives the same destination name, names
+// differing only in case are taken as the same source name
+func collision(what string, names []string, dest func(string) string) error {
+	seen := make(map[string]string)
+	for _, n := range names {
+		d := dest(n)
+		if prev, ok := seen[d]; ok && !strings.EqualFold(prev, n) {
+			return fmt.Errorf("%s: %s and %s both become %s", what, prev, n, d)
+		}
+		seen[d] = n
+	}
+	return nil
+}
+
+// Schema destination name of a source schema
+func (m *Mapping) Schema(schema string) string {
+	if m == nil {
+		return schema
+	}
+	if n, ok := lookupName(m.Schemas, schema); ok {
+		return n
+	}
+	return schema
+}
+
+// Table destination name of a source table
+func (m *Mapping) Table(table string) string {
+	if m == nil {
+		return table
+	}
+	return m.Tables.apply(table)
+}
+
+// Column destination name of a column of a source table
+func (m *Mapping) Column(table, column string) string {
+	if m == nil {
+		return column
+	}
+	if n, ok := lookupName(m.Columns.Names, table+"."+column); ok {
+		return n
+	}
+	return m.Columns.apply(column)
+}
+
+// apply renames name by the rules
+func (r NameRules) apply(name string) string {
+	if n, ok := lookupName(r.Names, name); ok {
+		return n
+	}
+	name = strings.TrimPrefix(name, r.StripPrefix)
+	name = strings.TrimSuffix(name, r.StripSuffix)
+	switch r.Case {
+	case "lower":
+		name = strings.ToLower(name)
+	case "upper":
+		name = strings.ToUpper(name)
+	case "snake":
+		name = snakeCase(name)
+	}
+	return r.AddPrefix + name + r.AddSuffix
+}
+
+// lookupName finds name in names, exactly or else ignoring case like mssql
+// does
+func lookupName(names map[string]string, name string) (string, bool) {
+	if n, ok := names[name]; ok {
+		return n, true
+	}
+	for k, n := range names {
+		if strings.EqualFold(k, name) {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// snakeCase turns CamelCase, HTTPServer and spaced names into lower case
+// words joined by underscores
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		switch {
+		case r == ' ' || r == '-':
+			r = '_'
+		case unicode.IsUpper(r) && i > 0:
+			prev := rs[i-1]
+			next := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && next {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// indexColumns destination column list of a source index column list,
+// "a","b"
+func (m *Mapping) indexColumns(table, columns string) string {
+	if m == nil {
+		return columns
+	}
+	cols := strings.Split(columns, ",")
+	for i, c := range cols {
+		cols[i] = `"` + m.Column(table, strings.Trim(strings.TrimSpace(c), `"`)) + `"`
+	}
+	return strings.Join(cols, ",")
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMapping(t *testing.T) {
+	for in, want := range map[string]string{
+		"OrderHeader": "order_header",
+		"OrderID":     "order_id",
+		"HTTPServer":  "http_server",
+		"ORDERS":      "orders",
+		"line2Total":  "line2_total",
+		"Cust No":     "cust_no",
+		"already_ok":  "already_ok",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	fn := filepath.Join(t.TempDir(), "rename.yml")
+	rules := `schemas:
+  dbo: public
+tables:
+  case: snake
+  strip_prefix: tbl
+  add_suffix: _src
+  names:
+    OrderHdr: orders
+columns:
+  case: lower
+  names:
+    OrderHdr.CustNo: customer_id
+    RowGUID: row_id
+`
+	if err := os.WriteFile(fn, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadMapping(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ got, want string }{
+		{m.Schema("dbo"), "public"},
+		{m.Schema("sales"), "sales"},
+		{m.Table("OrderHdr"), "orders"},
+		{m.Table("ORDERHDR"), "orders"},
+		{m.Table("tblCustomerNote"), "customer_note_src"},
+		{m.Column("OrderHdr", "CustNo"), "customer_id"},
+		{m.Column("Invoice", "CustNo"), "custno"},
+		{m.Column("Invoice", "rowguid"), "row_id"},
+		{(*Mapping)(nil).Table("ORDERS"), "ORDERS"},
+	} {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+	}
+
+	if err := os.WriteFile(fn, []byte("tables:\n  case: kebab\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMapping(fn); err == nil {
+		t.Error("unknown case should fail")
+	}
+}
+
+func TestGenerateRenamed(t *testing.T) {
+	cols := []Column{{ColumnName: "ID", DataType: "integer", IsNullable: "NOT NULL"}, {ColumnName: "CustNo", DataType: "integer"}}
+	pkey := []PKey{{PKey: "ID"}}
+	idxs := []Index{{Table: "ORDERS", Columns: `"CustNo"`}}
+	c := Conn{
+		Source:  &Database{Name: "erp", Driver: "mssql"},
+		Dest:    &Database{Driver: "pgx"},
+		SSchema: "dbo",
+		DSchema: "sales",
+		Mapping: &Mapping{Tables: NameRules{Case: "lower"}, Columns: NameRules{Case: "snake"}},
+	}
+
+	stage, swap, err := c.GenTableSwap("ORDERS", cols, pkey, idxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "sales"."orders__new" (`,
+		`"cust_no" integer`,
+		`CONSTRAINT "orders__new_pkey" PRIMARY KEY ("id")`,
+		`CREATE INDEX "orders__new_cust_no_idx" ON "sales"."orders__new" ("cust_no");`,
+	} {
+		if !strings.Contains(stage, want) {
+			t.Errorf("stage missing %s\n%s", want, stage)
+		}
+	}
+	if want := `ALTER INDEX "sales"."orders__new_cust_no_idx" RENAME TO "orders_cust_no_idx";`; !strings.Contains(swap, want) {
+		t.Errorf("swap missing %s\n%s", want, swap)
+	}
+
+	idx, _, sqlc := c.GenIndex(idxs[0])
+	if want := `CREATE INDEX IF NOT EXISTS "orders_cust_no_idx" ON "sales"."orders" ("cust_no");`; idx != `"orders_cust_no_idx"` || sqlc != want {
+		t.Errorf("index %s:\n got %s\nwant %s", idx, sqlc, want)
+	}
+
+	// the link reads the source names into the destination ones
+	_, sqlc, err = c.GenLink("ORDERS", cols, pkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"sales"."orderstemp"`,
+		"cust_no OPTIONS (column_name 'CustNo')",
+		"table_name 'dbo.ORDERS'",
+	} {
+		if !strings.Contains(sqlc, want) {
+			t.Errorf("link missing %s\n%s", want, sqlc)
+		}
+	}
+
+	c.Dest.Driver = "mssql"
+	_, sqlc, err = c.GenLink("ORDERS", cols, pkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"CustNo" "cust_no"`; !strings.Contains(sqlc, want) {
+		t.Errorf("mssql link missing %s\n%s", want, sqlc)
+	}
+
+	// the update procedure only sees the destination names
+	_, sqlc, err = c.GenUpdate("ORDERS", cols, pkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sqlc, "CustNo") || strings.Contains(sqlc, "ORDERS") {
+		t.Errorf("update uses source names:\n%s", sqlc)
+	}
+}
+
+func TestMappingCollisions(t *testing.T) {
+	for name, m := range map[string]*Mapping{
+		"schemas": {Schemas: map[string]string{"dbo": "public", "sales": "public"}},
+		"tables":  {Tables: NameRules{Names: map[string]string{"OrderHdr": "orders", "Orders2": "orders"}}},
+		"columns": {Columns: NameRules{Names: map[string]string{"Orders.CustNo": "customer_id", "Orders.CustID": "customer_id"}}},
+	} {
+		if err := m.Check(); err == nil {
+			t.Errorf("%s: duplicate destination names should fail", name)
+		}
+	}
+	// the same destination column in different tables is fine
+	m := &Mapping{Columns: NameRules{Names: map[string]string{"Orders.CustNo": "customer_id", "Invoices.CustID": "customer_id"}}}
+	if err := m.Check(); err != nil {
+		t.Error(err)
+	}
+
+	m = &Mapping{Schemas: map[string]string{"dbo": "public"}, Tables: NameRules{Case: "lower"}, Columns: NameRules{Case: "snake"}}
+	if err := m.CheckSchemas([]string{"dbo", "public"}); err == nil {
+		t.Error("dbo renamed onto the public schema should fail")
+	}
+	if err := m.CheckTables([]string{"Orders", "ORDERS"}); err != nil {
+		t.Errorf("names differing in case are one source table: %v", err)
+	}
+	if err := m.CheckTables([]string{"Orders", "orders"}); err != nil {
+		t.Errorf("names differing in case are one source table: %v", err)
+	}
+	if err := m.CheckTables([]string{"OrderLine", "orderline", "order_line"}); err != nil {
+		t.Errorf("lower case keeps order_line apart: %v", err)
+	}
+	if err := (&Mapping{Tables: NameRules{Case: "snake"}}).CheckTables([]string{"OrderLine", "order_line"}); err == nil {
+		t.Error("OrderLine and order_line both snake case to order_line, should fail")
+	}
+	if err := (*Mapping)(nil).CheckTables([]string{"a", "b"}); err != nil {
+		t.Error(err)
+	}
+
+	c := Conn{Source: &Database{Driver: "mssql"}, Dest: &Database{Driver: "pgx"}, DSchema: "sales", Mapping: m}
+	cols := []Column{{ColumnName: "CustNo", DataType: "integer"}, {ColumnName: "cust_no", DataType: "integer"}}
+	if _, _, err := c.GenTables("Orders", cols, nil); err == nil || !strings.Contains(err.Error(), "both become cust_no") {
+		t.Errorf("columns renamed onto each other: got %v", err)
+	}
+}
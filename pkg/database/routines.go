@@ -5,9 +5,6 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
-
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
 )
 
 //########
@@ -28,9 +25,7 @@ type RoutineList struct {
 }
 
 // GetRoutines returns list of routines and definitions
-func (c *Conn) GetRoutines(schema string, timeout int) ([]RoutineList, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetRoutines(ctx context.Context, schema string) ([]RoutineList, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -45,10 +40,12 @@ func (c *Conn) GetRoutines(schema string, timeout int) ([]RoutineList, error) {
 		WHERE ROUTINE_SCHEMA = ? 
 		AND ROUTINE_DEFINITION IS NOT NULL 
 		ORDER BY ROUTINE_NAME`
+	default:
+		return nil, unsupported("list routines", c.Source.Driver)
 	}
 	rr := []RoutineList{}
 	if err := c.Source.SelectContext(ctx, &rr, q, schema); err != nil {
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, catalogErr("list routines", schema, err)
 	}
 	return rr, nil
 }
@@ -85,33 +82,35 @@ func (c *Conn) GetRoutines(schema string, timeout int) ([]RoutineList, error) {
 // }
 
 // GetRoutineSchema returns routine and definition
-func (c *Conn) GetRoutineSchema(schema, routine string) (Routine, error) {
+func (c *Conn) GetRoutineSchema(ctx context.Context, schema, routine string) (Routine, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
 		q += `SELECT ROUTINE_NAME "ROUTINE_NAME"
-		ROUTINE_TYPE "ROUTINE_TYPE"
-		ROUTINE_DEFINITION "ROUTINE_DEFINITION"
-		CASE WHEN DATA_TYPE IS NULL THEN '' ELSE DATA_TYPE END "DATA_TYPE"
-		CASE WHEN EXTERNAL_LANGUAGE IS NULL THEN '' ELSE EXTERNAL_LANGUAGE END "EXTERNAL_LANGUAGE"
+		,ROUTINE_TYPE "ROUTINE_TYPE"
+		,ROUTINE_DEFINITION "ROUTINE_DEFINITION"
+		,CASE WHEN DATA_TYPE IS NULL THEN '' ELSE DATA_TYPE END "DATA_TYPE"
+		,CASE WHEN EXTERNAL_LANGUAGE IS NULL THEN '' ELSE EXTERNAL_LANGUAGE END "EXTERNAL_LANGUAGE"
 		FROM INFORMATION_SCHEMA.ROUTINES
 		WHERE ROUTINE_SCHEMA = $1 AND ROUTINE_NAME = $2
 		AND ROUTINE_DEFINITION IS NOT NULL
 		ORDER BY ROUTINE_NAME`
 	case "mssql":
 		q += `SELECT ROUTINE_NAME "ROUTINE_NAME"
-		ROUTINE_TYPE "ROUTINE_TYPE"
-		ROUTINE_DEFINITION "ROUTINE_DEFINITION"
-		CASE WHEN DATA_TYPE IS NULL THEN '' ELSE DATA_TYPE END "DATA_TYPE"
-		CASE WHEN EXTERNAL_LANGUAGE IS NULL THEN '' ELSE EXTERNAL_LANGUAGE END "EXTERNAL_LANGUAGE"
+		,ROUTINE_TYPE "ROUTINE_TYPE"
+		,ROUTINE_DEFINITION "ROUTINE_DEFINITION"
+		,CASE WHEN DATA_TYPE IS NULL THEN '' ELSE DATA_TYPE END "DATA_TYPE"
+		,CASE WHEN EXTERNAL_LANGUAGE IS NULL THEN '' ELSE EXTERNAL_LANGUAGE END "EXTERNAL_LANGUAGE"
 		FROM INFORMATION_SCHEMA.ROUTINES
 		WHERE ROUTINE_SCHEMA = ? AND ROUTINE_NAME = ?
 		AND ROUTINE_DEFINITION IS NOT NULL
 		ORDER BY ROUTINE_NAME`
+	default:
+		return Routine{}, unsupported("get routine", c.Source.Driver)
 	}
 	rr := Routine{}
-	if err := c.Source.Get(&rr, q, schema, routine); err != nil {
-		return Routine{}, fmt.Errorf("select: %w", err)
+	if err := c.Source.GetContext(ctx, &rr, q, schema, routine); err != nil {
+		return Routine{}, catalogErr("get routine", objectName(schema, routine), err)
 	}
 	return rr, nil
 }
@@ -148,7 +147,7 @@ func (c *Conn) GetRoutineSchema(schema, routine string) (Routine, error) {
 // }
 
 // GetRoutine gets procedure definition
-func (db *Database) GetRoutine(d Database, schema string, r Routine, dbg bool) {
+func (db *Database) GetRoutine(d Database, schema string, r Routine, dbg bool) error {
 	fmt.Printf("\n-- ROUTINE: %s.%s", schema, r.Name)
 	q := ""
 	if d.Driver == "postgres" || d.Driver == "pgx" {
@@ -177,9 +176,9 @@ func (db *Database) GetRoutine(d Database, schema string, r Routine, dbg bool) {
 	} else {
 		t := strings.Replace(r.Name, "/", "_", -1)
 		fname := fmt.Sprintf("%s.%s.%s.%s.sql", d.Database, schema, t, r.Type)
-		f, err := os.Create(fname)
-		ec.CheckErr(err)
-		defer f.Close()
-		f.Write([]byte(q))
+		if err := os.WriteFile(fname, []byte(q), 0o666); err != nil {
+			return fmt.Errorf("routine %s: %w", objectName(schema, r.Name), err)
+		}
 	}
+	return nil
 }
@@ -2,8 +2,6 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"time"
 )
 
 //########
@@ -16,13 +14,12 @@ type Schema struct {
 }
 
 // GetSchemas returns schema list
-func (db *Database) GetSchemas(timeout int) ([]Schema, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (db *Database) GetSchemas(ctx context.Context) ([]Schema, error) {
 	q := ""
-	if db.Driver == "postgres" || db.Driver == "pgx" {
+	switch db.Driver {
+	case "postgres", "pgx":
 		q = "select schema_name \"SCHEMA_NAME\" from information_schema.schemata where schema_name not in ('pg_catalog','information_schema') order by schema_name"
-	} else if db.Driver == "mssql" {
+	case "mssql":
 		q = "select \"SCHEMA_NAME\" from INFORMATION_SCHEMA.SCHEMATA where SCHEMA_NAME not in ("
 		q += "'INFORMATION_SCHEMA',"
 		q += "'db_accessadmin',"
@@ -36,10 +33,12 @@ func (db *Database) GetSchemas(timeout int) ([]Schema, error) {
 		q += "'db_securityadmin',"
 		q += "'sys'"
 		q += ") order by SCHEMA_NAME"
+	default:
+		return nil, unsupported("list schemas", db.Driver)
 	}
 	ss := []Schema{}
 	if err := db.SelectContext(ctx, &ss, q); err != nil {
-		return nil, fmt.Errorf("select: %v", err)
+		return nil, catalogErr("list schemas", db.Database, err)
 	}
 	return ss, nil
 }
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//########
+// Pool and Session
+//########
+
+// Pool connection pool limits, zero values keep the database/sql defaults
+type Pool struct {
+	MaxOpen         int           `yaml:"max_open" json:"max_open,omitempty"`
+	MaxIdle         int           `yaml:"max_idle" json:"max_idle,omitempty"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" json:"conn_max_idle_time,omitempty"`
+}
+
+// Session settings applied to every new connection
+type Session struct {
+	ApplicationName  string        `yaml:"application_name" json:"application_name,omitempty"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" json:"statement_timeout,omitempty"` // postgres only
+	LockTimeout      time.Duration `yaml:"lock_timeout" json:"lock_timeout,omitempty"`
+	SearchPath       string        `yaml:"search_path" json:"search_path,omitempty"` // postgres only
+	ConnectTimeout   time.Duration `yaml:"connect_timeout" json:"connect_timeout,omitempty"`
+}
+
+// apply sets the pool limits on the opened database
+func (p Pool) apply(db *Database) {
+	if p.MaxOpen > 0 {
+		db.SetMaxOpenConns(p.MaxOpen)
+	}
+	if p.MaxIdle > 0 {
+		db.SetMaxIdleConns(p.MaxIdle)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// check reports invalid limits
+func (p Pool) check() error {
+	if p.MaxOpen < 0 || p.MaxIdle < 0 || p.ConnMaxLifetime < 0 || p.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("pool: settings cannot be negative")
+	}
+	if p.MaxOpen > 0 && p.MaxIdle > p.MaxOpen {
+		return fmt.Errorf("pool: max_idle cannot exceed max_open")
+	}
+	return nil
+}
+
+// check reports invalid settings and settings the driver cannot honor
+func (s Session) check(driver string) error {
+	if s.StatementTimeout < 0 || s.LockTimeout < 0 || s.ConnectTimeout < 0 {
+		return fmt.Errorf("session: timeouts cannot be negative")
+	}
+	if driver != "mssql" {
+		return nil
+	}
+	if s.StatementTimeout > 0 {
+		return fmt.Errorf("session: mssql has no statement_timeout, use the query timeout")
+	}
+	if s.SearchPath != "" {
+		return fmt.Errorf("session: mssql has no search_path, the default schema is set per user")
+	}
+	return nil
+}
+
+// pgParams sets the session parameters on a parsed postgres config,
+// parameters given in the connection URI are kept
+func (s Session) pgParams(cfg *pgx.ConnConfig) {
+	set := func(k, v string) {
+		if _, ok := cfg.RuntimeParams[k]; !ok && v != "" {
+			cfg.RuntimeParams[k] = v
+		}
+	}
+	set("application_name", s.ApplicationName)
+	if s.StatementTimeout > 0 {
+		set("statement_timeout", strconv.FormatInt(s.StatementTimeout.Milliseconds(), 10))
+	}
+	if s.LockTimeout > 0 {
+		set("lock_timeout", strconv.FormatInt(s.LockTimeout.Milliseconds(), 10))
+	}
+	set("search_path", s.SearchPath)
+	if s.ConnectTimeout > 0 && cfg.ConnectTimeout == 0 {
+		cfg.ConnectTimeout = s.ConnectTimeout
+	}
+}
+
+// mssqlParams go-mssqldb connection parameters. Queries are bounded by
+// their context, so the connection itself never times out.
+func (s Session) mssqlParams() string {
+	p := []string{"connection timeout=0"}
+	if s.ConnectTimeout > 0 {
+		p = append(p, "dial timeout="+strconv.Itoa(int(s.ConnectTimeout.Seconds())))
+	}
+	if s.ApplicationName != "" {
+		p = append(p, "app name="+s.ApplicationName)
+	}
+	return strings.Join(p, ";")
+}
+
+// mssqlInitSQL statements run on every new or reset mssql connection
+func (s Session) mssqlInitSQL() string {
+	if s.LockTimeout > 0 {
+		return fmt.Sprintf("SET LOCK_TIMEOUT %d", s.LockTimeout.Milliseconds())
+	}
+	return ""
+}
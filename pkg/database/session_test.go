@@ -0,0 +1,61 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestSessionParams(t *testing.T) {
+	s := Session{ApplicationName: "dbtools", StatementTimeout: time.Minute, LockTimeout: 5 * time.Second,
+		SearchPath: "app,public", ConnectTimeout: 15 * time.Second}
+
+	cfg, err := pgx.ParseConfig("postgres://u@db1/app?application_name=etl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.pgParams(cfg)
+	want := map[string]string{"application_name": "etl", "statement_timeout": "60000", "lock_timeout": "5000", "search_path": "app,public"}
+	for k, v := range want {
+		if cfg.RuntimeParams[k] != v {
+			t.Errorf("%s = %q, want %q", k, cfg.RuntimeParams[k], v)
+		}
+	}
+	if cfg.ConnectTimeout != 15*time.Second {
+		t.Errorf("connect timeout %v", cfg.ConnectTimeout)
+	}
+
+	db := Database{Driver: "mssql", Hostname: "db2", Database: "erp", Username: "sa", Session: Session{ApplicationName: "dbtools", ConnectTimeout: 15 * time.Second}}
+	db.GetURI()
+	if !strings.HasSuffix(db.URI, ";connection timeout=0;dial timeout=15;app name=dbtools;keepAlive=30") {
+		t.Errorf("mssql dsn %s", db.URI)
+	}
+	if got := s.mssqlInitSQL(); got != "SET LOCK_TIMEOUT 5000" {
+		t.Errorf("mssql init %q", got)
+	}
+	if err := s.check("mssql"); err == nil {
+		t.Error("mssql statement_timeout: expected error")
+	}
+}
+
+func TestCheckSettings(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		db   Database
+		ok   bool
+	}{
+		{"defaults", Database{Driver: "pgx"}, true},
+		{"pool", Database{Driver: "pgx", Pool: Pool{MaxOpen: 8, MaxIdle: 4}}, true},
+		{"idle over open", Database{Driver: "pgx", Pool: Pool{MaxOpen: 2, MaxIdle: 4}}, false},
+		{"negative pool", Database{Driver: "pgx", Pool: Pool{ConnMaxLifetime: -time.Second}}, false},
+		{"negative timeout", Database{Driver: "pgx", Session: Session{LockTimeout: -time.Second}}, false},
+		{"mssql search path", Database{Driver: "mssql", Session: Session{SearchPath: "dbo"}}, false},
+		{"tls mode", Database{Driver: "pgx", TLS: TLS{Mode: "verify"}}, false},
+	} {
+		if err := c.db.CheckSettings(); (err == nil) != c.ok {
+			t.Errorf("%s: CheckSettings() = %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package database
+
+// sqlite is only used by ad-hoc queries, the driver is left out of the
+// default build
+import _ "modernc.org/sqlite" // pure go sqlite driver
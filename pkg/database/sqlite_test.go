@@ -0,0 +1,3 @@
+package database
+
+import _ "modernc.org/sqlite" // the tests run on sqlite whatever the build tags
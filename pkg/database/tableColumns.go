@@ -2,8 +2,6 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"time"
 )
 
 //########
@@ -24,9 +22,7 @@ type Column struct {
 	DataType      string `db:"DATA_TYPE"`
 }
 
-func (c *Conn) GetColumnDetail(t string, timeout int) ([]Column, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetColumnDetail(ctx context.Context, t string) ([]Column, error) {
 	q := ""
 	q += `SELECT C.COLUMN_NAME AS "COLUMN_NAME"
 	,CASE WHEN IS_NULLABLE = 'NO' THEN 'NOT NULL' ELSE '' END AS "IS_NULLABLE"`
@@ -119,10 +115,12 @@ func (c *Conn) GetColumnDetail(t string, timeout int) ([]Column, error) {
 		q += `FROM INFORMATION_SCHEMA.COLUMNS C
 		WHERE C.TABLE_CATALOG = $1 AND C.TABLE_SCHEMA = $2 AND C.TABLE_NAME = $3
 		ORDER BY C.TABLE_CATALOG,C.TABLE_SCHEMA,C.TABLE_NAME,ORDINAL_POSITION;`
+	default:
+		return nil, unsupported("get columns", c.Source.Driver)
 	}
 	columnnames := []Column{}
 	if err := c.Source.SelectContext(ctx, &columnnames, q, c.Source.Database, c.SSchema, t); err != nil {
-		return nil, fmt.Errorf("select: %v", err)
+		return nil, catalogErr("get columns", objectName(c.SSchema, t), err)
 	}
 	return columnnames, nil
 }
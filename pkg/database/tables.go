@@ -2,10 +2,7 @@ package database
 
 import (
 	"context"
-	"fmt"
-	"time"
-
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
+	"errors"
 )
 
 //########
@@ -18,9 +15,7 @@ type Table struct {
 }
 
 // GetTableList returns table list
-func (c *Conn) GetTables(schemaName, ttype string, timeout int) ([]Table, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetTables(ctx context.Context, schemaName, ttype string) ([]Table, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -33,41 +28,68 @@ func (c *Conn) GetTables(schemaName, ttype string, timeout int) ([]Table, error)
 		FROM INFORMATION_SCHEMA.TABLES 
 		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = ?
 		ORDER BY TABLE_NAME`
+	default:
+		return nil, unsupported("list tables", c.Source.Driver)
 	}
 	tt := []Table{}
 	if err := c.Source.SelectContext(ctx, &tt, q, schemaName, ttype); err != nil {
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, catalogErr("list tables", schemaName, err)
 	}
 	return tt, nil
 }
 
-// GetTableSchema gets table definition
-func (c *Conn) GetTableSchema(table string, timeout int) (sqld, sqlc, sqldi, sqlci string) {
-	scols, err := c.GetColumnDetail(table, timeout)
-	ec.CheckErr(err)
-	pcols, err := c.GetPKey(table, timeout)
-	ec.CheckErr(err)
-	sqld, sqlc = c.GenTables(table, scols, pcols)
-	sqldi, sqlci = c.GenTableIndexSQL(table)
+// GetTableSchema gets table definition, stage builds the table under a
+// staging name and swap replaces the destination table with it
+func (c *Conn) GetTableSchema(ctx context.Context, table string) (stage, swap string, err error) {
+	scols, pcols, err := c.tableKeys(ctx, table)
+	if err != nil {
+		return
+	}
+	idxs, err := c.GetTableIndexSchema(ctx, table)
+	if err != nil {
+		return
+	}
+	stage, swap, err = c.GenTableSwap(table, scols, pcols, idxs)
 	return
 }
 
 // GetForeignTableSchema gets table definition
-func (c *Conn) GetForeignTableSchema(table string, timeout int) (sqld, sqlc string) {
-	scols, err := c.GetColumnDetail(table, timeout)
-	ec.CheckErr(err)
-	pcols, err := c.GetPKey(table, timeout)
-	ec.CheckErr(err)
-	sqld, sqlc = c.GenLink(table, scols, pcols)
+func (c *Conn) GetForeignTableSchema(ctx context.Context, table string) (sqld, sqlc string, err error) {
+	scols, pcols, err := c.tableKeys(ctx, table)
+	if err != nil {
+		return
+	}
+	sqld, sqlc, err = c.GenLink(table, scols, pcols)
 	return
 }
 
 // GetUpdateTableSchema gets table definition
-func (c *Conn) GetUpdateTableSchema(table string, timeout int) (sqld, sqlc string) {
-	scols, err := c.GetColumnDetail(table, timeout)
-	ec.CheckErr(err)
-	pcols, err := c.GetPKey(table, timeout)
-	ec.CheckErr(err)
-	sqld, sqlc = c.GenUpdate(table, scols, pcols)
+func (c *Conn) GetUpdateTableSchema(ctx context.Context, table string) (sqld, sqlc string, err error) {
+	scols, pcols, err := c.tableKeys(ctx, table)
+	if err != nil {
+		return
+	}
+	if len(scols) == 0 {
+		return "", "", catalogErr("get columns", objectName(c.SSchema, table), errors.New("table has no columns"))
+	}
+	sqld, sqlc, err = c.GenUpdate(table, scols, pcols)
 	return
 }
+
+// tableKeys gets the columns and primary key of table
+func (c *Conn) tableKeys(ctx context.Context, table string) ([]Column, []PKey, error) {
+	switch c.Dest.Driver {
+	case "postgres", "pgx", "mssql":
+	default:
+		return nil, nil, unsupported("generate ddl", c.Dest.Driver)
+	}
+	scols, err := c.GetColumnDetail(ctx, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	pcols, err := c.GetPKey(ctx, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scols, pcols, nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//########
+// Templates
+//########
+
+// templateFS built-in templates, <kind>.<dialect>.tmpl
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// TableModel data the ddl templates are executed with
+type TableModel struct {
+	Driver       string // destination driver
+	Schema       string // destination schema
+	Table        string // destination table
+	SourceSchema string
+	SourceTable  string
+	Source       *Database // source host, for links
+	Columns      []Column  // destination column names
+	PKey         []PKey
+	PKName       string   // postgres primary key name, empty for the default
+	Indexes      []Index  // table indexes when known
+	Values       []Column // columns not in the primary key
+
+	sourceColumns map[string]string // renamed destination column: source column
+	sourceNames   []string          // source names of the renamed columns
+}
+
+// SourceColumn source name of a destination column
+func (m TableModel) SourceColumn(column string) string {
+	if n, ok := m.sourceColumns[column]; ok {
+		return n
+	}
+	return column
+}
+
+// Templates ddl templates by kind and dialect, each defines "drop" and
+// "create"
+type Templates struct {
+	sets map[string]*template.Template
+}
+
+// templateFuncs functions available in the templates
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"replace": strings.ReplaceAll,
+	"join":    strings.Join,
+	// last reports whether i is the last index of list
+	"last": func(i int, list any) bool { return i == reflect.ValueOf(list).Len()-1 },
+}
+
+// defaultTemplates built-in templates, parsed once
+var defaultTemplates = sync.OnceValue(parseDefaults)
+
+// parseDefaults parses the built-in templates
+func parseDefaults() *Templates {
+	t := &Templates{sets: make(map[string]*template.Template)}
+	files, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		key := strings.TrimSuffix(f.Name(), ".tmpl")
+		t.sets[key] = template.Must(template.New(f.Name()).Funcs(templateFuncs).ParseFS(templateFS, "templates/"+f.Name()))
+	}
+	return t
+}
+
+// LoadTemplates returns the built-in templates with the <kind>.<dialect>.tmpl
+// files of dir on top: table, link or update for postgres or mssql. A file
+// only needs to define the templates it changes.
+func LoadTemplates(dir string) (*Templates, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("templates: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("templates: %w", err)
+	}
+	t := parseDefaults()
+	for _, fn := range files {
+		set, ok := t.sets[strings.TrimSuffix(filepath.Base(fn), ".tmpl")]
+		if !ok {
+			return nil, fmt.Errorf("templates: %s: expected <kind>.<dialect>.tmpl, kind table, link or update, dialect postgres or mssql", fn)
+		}
+		if _, err := set.ParseFiles(fn); err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// templateDialect dialect of the templates of driver, empty when it has none
+func templateDialect(driver string) string {
+	switch driver {
+	case "postgres", "pgx":
+		return "postgres"
+	case "mssql":
+		return "mssql"
+	}
+	return ""
+}
+
+// model template data of source table on the destination, with the
+// table and column names renamed by the mapping
+func (c *Conn) model(table string, cols []Column, pkey []PKey) TableModel {
+	m := TableModel{
+		Driver:        c.Dest.Driver,
+		Schema:        c.DSchema,
+		Table:         c.Mapping.Table(table),
+		SourceSchema:  c.SSchema,
+		SourceTable:   table,
+		Source:        c.Source,
+		Columns:       cols,
+		PKey:          pkey,
+		sourceColumns: make(map[string]string),
+	}
+	if c.Mapping != nil {
+		m.Columns = make([]Column, len(cols))
+		for i, col := range cols {
+			m.sourceNames = append(m.sourceNames, col.ColumnName)
+			col.ColumnName = c.Mapping.Column(table, col.ColumnName)
+			if col.ColumnName != cols[i].ColumnName {
+				m.sourceColumns[col.ColumnName] = cols[i].ColumnName
+			}
+			m.Columns[i] = col
+		}
+		m.PKey = make([]PKey, len(pkey))
+		for i, p := range pkey {
+			m.PKey[i] = PKey{PKey: c.Mapping.Column(table, p.PKey)}
+		}
+	}
+	m.Values = trimCols(m.Columns, m.PKey)
+	return m
+}
+
+// render executes the drop and create templates of kind for the
+// destination dialect, both are empty when it has none
+func (c *Conn) render(kind string, m TableModel) (sqld, sqlc string, err error) {
+	t := c.Templates
+	if t == nil {
+		t = defaultTemplates()
+	}
+	set := t.sets[kind+"."+templateDialect(c.Dest.Driver)]
+	if set == nil {
+		return "", "", nil
+	}
+	if err := collision("columns of "+m.SourceTable, m.sourceNames, func(col string) string { return c.Mapping.Column(m.SourceTable, col) }); err != nil {
+		return "", "", err
+	}
+	var b strings.Builder
+	if err := set.ExecuteTemplate(&b, "drop", m); err != nil {
+		return "", "", templateErr(kind, m, err)
+	}
+	sqld = b.String()
+	b.Reset()
+	if err := set.ExecuteTemplate(&b, "create", m); err != nil {
+		return "", "", templateErr(kind, m, err)
+	}
+	return sqld, b.String(), nil
+}
+
+// templateErr error executing the template of kind for the table of m
+func templateErr(kind string, m TableModel, err error) error {
+	return fmt.Errorf("%s template %s: %w", kind, objectName(m.Schema, m.Table), err)
+}
@@ -0,0 +1,60 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."pairs";
+-- GenTables create
+
+CREATE TABLE "sales"."pairs" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+PRIMARY KEY ("id","name")
+)
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."pairs";
+-- genTable pkName create
+
+CREATE TABLE "sales"."pairs" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+PRIMARY KEY ("id","name")
+)
+
+-- GenLink drop
+
+DROP VIEW IF EXISTS "sales"."pairstemp";
+
+-- GenLink create
+CREATE VIEW "sales"."pairstemp" AS
+SELECT
+"id" "id",
+"name" COLLATE database_default "name"
+FROM "srv"."erp"."dbo"."pairs";
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_pairs";
+-- GenUpdate create
+
+CREATE PROCEDURE "sales"."upd_pairs" AS
+BEGIN
+IF OBJECT_ID('tempdb..#pairs','U') IS NOT NULL DROP TABLE tempdb.#pairs
+SELECT * INTO #pairs FROM "sales"."pairstemp"
+DELETE "sales"."pairs"
+FROM "sales"."pairs"
+LEFT JOIN #pairs "temppairs" ON
+"pairs"."id" = "pairstemp"."id" AND 
+"pairs"."name" = "pairstemp"."name"
+WHERE "pairstemp"."id" IS NULL
+INSERT INTO "sales"."pairs"
+SELECT
+"pairstemp"."id" "id",
+"pairstemp"."name" "name"
+FROM "sales"."pairs"
+RIGHT JOIN #pairs "pairstemp" ON
+"pairs"."id" = "pairstemp"."id" AND 
+"pairs"."name" = "pairstemp"."name"
+WHERE "pairs"."id" IS NULL
+IF OBJECT_ID('tempdb..#pairs','U') IS NOT NULL DROP TABLE tempdb.#pairs
+END;
+
@@ -0,0 +1,65 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."pairs" CASCADE;
+-- GenTables create
+
+CREATE TABLE IF NOT EXISTS "sales"."pairs" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+PRIMARY KEY ("id","name")
+);
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."pairs" CASCADE;
+-- genTable pkName create
+
+CREATE TABLE IF NOT EXISTS "sales"."pairs" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+CONSTRAINT "pairs_pkey" PRIMARY KEY ("id","name")
+);
+
+-- GenLink drop
+
+DROP FOREIGN TABLE IF EXISTS "sales"."pairstemp" CASCADE;
+
+-- GenLink create
+CREATE FOREIGN TABLE IF NOT EXISTS "sales"."pairstemp" (
+id,
+name
+)
+SERVER prod 
+OPTIONS (table_name 'dbo.pairs', row_estimate_method 'showplan_all', match_column_names '0');
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_pairs"();
+-- GenUpdate create
+
+CREATE OR REPLACE PROCEDURE "sales"."upd_pairs"()
+LANGUAGE plpgsql
+AS $procedure$
+BEGIN
+DELETE
+FROM "sales"."pairs"
+USING "sales"."pairs" AS d
+LEFT OUTER JOIN "sales"."pairstemp" "pairstemp" ON
+d."id" = "pairstemp"."id" AND 
+d."name" = "pairstemp"."name"
+WHERE
+"pairs"."id" = d."id"  AND 
+"pairs"."name" = d."name" 
+AND "pairstemp"."id" IS NULL;
+INSERT INTO "sales"."pairs"
+SELECT
+"pairstemp"."id" "id",
+"pairstemp"."name" "name"
+FROM "sales"."pairs"
+RIGHT JOIN "sales"."pairstemp" "pairstemp" ON
+"pairs"."id" = "pairstemp"."id" AND 
+"pairs"."name" = "pairstemp"."name"
+WHERE "pairs"."id" IS NULL;
+END
+$procedure$;
+
@@ -0,0 +1,74 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "ep1"."ORDERS";
+-- GenTables create
+
+CREATE TABLE "ep1"."ORDERS" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate(),
+PRIMARY KEY ("id","name")
+)
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "ep1"."ORDERS";
+-- genTable pkName create
+
+CREATE TABLE "ep1"."ORDERS" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate(),
+PRIMARY KEY ("id","name")
+)
+
+-- GenLink drop
+
+DROP VIEW IF EXISTS "ep1"."ORDERSTEMP";
+
+-- GenLink create
+CREATE VIEW "ep1"."ORDERSTEMP" AS
+SELECT
+"id" "id",
+"name" COLLATE database_default "name",
+"created" "created"
+FROM "srv"."erp"."dbo"."ORDERS";
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "ep1"."upd_ORDERS";
+-- GenUpdate create
+
+CREATE PROCEDURE "ep1"."upd_ORDERS" AS
+BEGIN
+IF OBJECT_ID('tempdb..#ORDERS','U') IS NOT NULL DROP TABLE tempdb.#ORDERS
+SELECT * INTO #ORDERS FROM "ep1"."ORDERSTEMP"
+DELETE "ep1"."ORDERS"
+FROM "ep1"."ORDERS"
+LEFT JOIN #ORDERS "TEMPORDERS" ON
+"ORDERS"."id" = "ORDERSTEMP"."id" AND 
+"ORDERS"."name" = "ORDERSTEMP"."name"
+WHERE "ORDERSTEMP"."id" IS NULL
+UPDATE "ep1"."ORDERS"
+SET
+"created" = "ORDERSTEMP"."created"
+FROM #ORDERS "TEMPORDERS"
+JOIN "ep1"."ORDERS" ON
+"ORDERS"."id" = "ORDERSTEMP"."id" AND 
+"ORDERS"."name" = "ORDERSTEMP"."name"
+WHERE (
+"ORDERS"."created" <> "ORDERSTEMP"."created"
+)
+INSERT INTO "ep1"."ORDERS"
+SELECT
+"ORDERSTEMP"."id" "id",
+"ORDERSTEMP"."name" "name",
+"ORDERSTEMP"."created" "created"
+FROM "ep1"."ORDERS"
+RIGHT JOIN #ORDERS "ORDERSTEMP" ON
+"ORDERS"."id" = "ORDERSTEMP"."id" AND 
+"ORDERS"."name" = "ORDERSTEMP"."name"
+WHERE "ORDERS"."id" IS NULL
+IF OBJECT_ID('tempdb..#ORDERS','U') IS NOT NULL DROP TABLE tempdb.#ORDERS
+END;
+
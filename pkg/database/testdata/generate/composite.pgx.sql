@@ -0,0 +1,79 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "ep1"."ORDERS" CASCADE;
+-- GenTables create
+
+CREATE TABLE IF NOT EXISTS "ep1"."ORDERS" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+PRIMARY KEY ("id","name")
+);
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "ep1"."ORDERS" CASCADE;
+-- genTable pkName create
+
+CREATE TABLE IF NOT EXISTS "ep1"."ORDERS" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+CONSTRAINT "ORDERS_pkey" PRIMARY KEY ("id","name")
+);
+
+-- GenLink drop
+
+DROP FOREIGN TABLE IF EXISTS "ep1"."ORDERSTEMP" CASCADE;
+
+-- GenLink create
+CREATE FOREIGN TABLE IF NOT EXISTS "ep1"."ORDERSTEMP" (
+id,
+name,
+created
+)
+SERVER prod 
+OPTIONS (table_name 'dbo.ORDERS', row_estimate_method 'showplan_all', match_column_names '0');
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "ep1"."upd_ORDERS"();
+-- GenUpdate create
+
+CREATE OR REPLACE PROCEDURE "ep1"."upd_ORDERS"()
+LANGUAGE plpgsql
+AS $procedure$
+BEGIN
+DELETE
+FROM "ep1"."ORDERS"
+USING "ep1"."ORDERS" AS d
+LEFT OUTER JOIN "ep1"."ORDERSTEMP" "ORDERSTEMP" ON
+d."id" = "ORDERSTEMP"."id" AND 
+d."name" = "ORDERSTEMP"."name"
+WHERE
+"ORDERS"."id" = d."id"  AND 
+"ORDERS"."name" = d."name" 
+AND "ORDERSTEMP"."id" IS NULL;
+UPDATE "ep1"."ORDERS"
+SET
+"created" = "ORDERSTEMP"."created"
+FROM "ep1"."ORDERSTEMP" "ORDERSTEMP"
+WHERE
+"ORDERS"."id" = "ORDERSTEMP"."id" AND 
+"ORDERS"."name" = "ORDERSTEMP"."name"
+AND (
+"ORDERS"."created" <> "ORDERSTEMP"."created"
+);
+INSERT INTO "ep1"."ORDERS"
+SELECT
+"ORDERSTEMP"."id" "id",
+"ORDERSTEMP"."name" "name",
+"ORDERSTEMP"."created" "created"
+FROM "ep1"."ORDERS"
+RIGHT JOIN "ep1"."ORDERSTEMP" "ORDERSTEMP" ON
+"ORDERS"."id" = "ORDERSTEMP"."id" AND 
+"ORDERS"."name" = "ORDERSTEMP"."name"
+WHERE "ORDERS"."id" IS NULL;
+END
+$procedure$;
+
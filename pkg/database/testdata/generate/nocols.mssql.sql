@@ -0,0 +1,25 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."gone";
+-- GenTables create
+
+CREATE TABLE "sales"."gone" (
+)
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."gone";
+-- genTable pkName create
+
+CREATE TABLE "sales"."gone" (
+)
+
+-- GenLink drop
+
+DROP VIEW IF EXISTS "sales"."gonetemp";
+
+-- GenLink create
+CREATE VIEW "sales"."gonetemp" AS
+SELECT
+FROM "srv"."erp"."dbo"."gone";
+
@@ -0,0 +1,26 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."gone" CASCADE;
+-- GenTables create
+
+CREATE TABLE IF NOT EXISTS "sales"."gone" (
+);
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."gone" CASCADE;
+-- genTable pkName create
+
+CREATE TABLE IF NOT EXISTS "sales"."gone" (
+);
+
+-- GenLink drop
+
+DROP FOREIGN TABLE IF EXISTS "sales"."gonetemp" CASCADE;
+
+-- GenLink create
+CREATE FOREIGN TABLE IF NOT EXISTS "sales"."gonetemp" (
+)
+SERVER prod 
+OPTIONS (table_name 'dbo.gone', row_estimate_method 'showplan_all', match_column_names '0');
+
@@ -0,0 +1,67 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."log";
+-- GenTables create
+
+CREATE TABLE "sales"."log" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate()
+)
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."log";
+-- genTable pkName create
+
+CREATE TABLE "sales"."log" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate()
+)
+
+-- GenLink drop
+
+DROP VIEW IF EXISTS "sales"."logtemp";
+
+-- GenLink create
+CREATE VIEW "sales"."logtemp" AS
+SELECT
+"id" "id",
+"name" COLLATE database_default "name",
+"created" "created"
+FROM "srv"."erp"."dbo"."log";
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_log";
+-- GenUpdate create
+
+CREATE PROCEDURE "sales"."upd_log" AS
+BEGIN
+IF OBJECT_ID('tempdb..#log','U') IS NOT NULL DROP TABLE tempdb.#log
+SELECT * INTO #log FROM "sales"."logtemp"
+DELETE "sales"."log"
+FROM "sales"."log"
+LEFT JOIN #log "templog" ONWHERE "logtemp"."id" IS NULL
+UPDATE "sales"."log"
+SET
+"id" = "logtemp"."id",
+"name" = "logtemp"."name",
+"created" = "logtemp"."created"
+FROM #log "templog"
+JOIN "sales"."log" ONWHERE (
+"log"."id" <> "logtemp"."id" OR 
+"log"."name" <> "logtemp"."name" OR 
+"log"."created" <> "logtemp"."created"
+)
+INSERT INTO "sales"."log"
+SELECT
+"logtemp"."id" "id",
+"logtemp"."name" "name",
+"logtemp"."created" "created"
+FROM "sales"."log"
+RIGHT JOIN #log "logtemp" ONWHERE "log"."id" IS NULL
+IF OBJECT_ID('tempdb..#log','U') IS NOT NULL DROP TABLE tempdb.#log
+END;
+
@@ -0,0 +1,69 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."log" CASCADE;
+-- GenTables create
+
+CREATE TABLE IF NOT EXISTS "sales"."log" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."log" CASCADE;
+-- genTable pkName create
+
+CREATE TABLE IF NOT EXISTS "sales"."log" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- GenLink drop
+
+DROP FOREIGN TABLE IF EXISTS "sales"."logtemp" CASCADE;
+
+-- GenLink create
+CREATE FOREIGN TABLE IF NOT EXISTS "sales"."logtemp" (
+id,
+name,
+created
+)
+SERVER prod 
+OPTIONS (table_name 'dbo.log', row_estimate_method 'showplan_all', match_column_names '0');
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_log"();
+-- GenUpdate create
+
+CREATE OR REPLACE PROCEDURE "sales"."upd_log"()
+LANGUAGE plpgsql
+AS $procedure$
+BEGIN
+DELETE
+FROM "sales"."log"
+USING "sales"."log" AS d
+LEFT OUTER JOIN "sales"."logtemp" "logtemp" ONWHEREAND "logtemp"."id" IS NULL;
+UPDATE "sales"."log"
+SET
+"id" = "logtemp"."id",
+"name" = "logtemp"."name",
+"created" = "logtemp"."created"
+FROM "sales"."logtemp" "logtemp"
+WHEREAND (
+"log"."id" <> "logtemp"."id" OR 
+"log"."name" <> "logtemp"."name" OR 
+"log"."created" <> "logtemp"."created"
+);
+INSERT INTO "sales"."log"
+SELECT
+"logtemp"."id" "id",
+"logtemp"."name" "name",
+"logtemp"."created" "created"
+FROM "sales"."log"
+RIGHT JOIN "sales"."logtemp" "logtemp" ONWHERE "log"."id" IS NULL;
+END
+$procedure$;
+
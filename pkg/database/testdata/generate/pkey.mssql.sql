@@ -0,0 +1,73 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."orders";
+-- GenTables create
+
+CREATE TABLE "sales"."orders" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate(),
+PRIMARY KEY ("id")
+)
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."orders";
+-- genTable pkName create
+
+CREATE TABLE "sales"."orders" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT getdate(),
+PRIMARY KEY ("id")
+)
+
+-- GenLink drop
+
+DROP VIEW IF EXISTS "sales"."orderstemp";
+
+-- GenLink create
+CREATE VIEW "sales"."orderstemp" AS
+SELECT
+"id" "id",
+"name" COLLATE database_default "name",
+"created" "created"
+FROM "srv"."erp"."dbo"."orders";
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_orders";
+-- GenUpdate create
+
+CREATE PROCEDURE "sales"."upd_orders" AS
+BEGIN
+IF OBJECT_ID('tempdb..#orders','U') IS NOT NULL DROP TABLE tempdb.#orders
+SELECT * INTO #orders FROM "sales"."orderstemp"
+DELETE "sales"."orders"
+FROM "sales"."orders"
+LEFT JOIN #orders "temporders" ON
+"orders"."id" = "orderstemp"."id"
+WHERE "orderstemp"."id" IS NULL
+UPDATE "sales"."orders"
+SET
+"name" = "orderstemp"."name",
+"created" = "orderstemp"."created"
+FROM #orders "temporders"
+JOIN "sales"."orders" ON
+"orders"."id" = "orderstemp"."id"
+WHERE (
+"orders"."name" <> "orderstemp"."name" OR 
+"orders"."created" <> "orderstemp"."created"
+)
+INSERT INTO "sales"."orders"
+SELECT
+"orderstemp"."id" "id",
+"orderstemp"."name" "name",
+"orderstemp"."created" "created"
+FROM "sales"."orders"
+RIGHT JOIN #orders "orderstemp" ON
+"orders"."id" = "orderstemp"."id"
+WHERE "orders"."id" IS NULL
+IF OBJECT_ID('tempdb..#orders','U') IS NOT NULL DROP TABLE tempdb.#orders
+END;
+
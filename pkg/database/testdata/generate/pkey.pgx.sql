@@ -0,0 +1,77 @@
+-- GenTables drop
+
+DROP TABLE IF EXISTS "sales"."orders" CASCADE;
+-- GenTables create
+
+CREATE TABLE IF NOT EXISTS "sales"."orders" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+PRIMARY KEY ("id")
+);
+
+-- genTable pkName drop
+
+DROP TABLE IF EXISTS "sales"."orders" CASCADE;
+-- genTable pkName create
+
+CREATE TABLE IF NOT EXISTS "sales"."orders" (
+"id" integer NOT NULL,
+"name" VARCHAR  DEFAULT 'x',
+"created" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+CONSTRAINT "orders_pkey" PRIMARY KEY ("id")
+);
+
+-- GenLink drop
+
+DROP FOREIGN TABLE IF EXISTS "sales"."orderstemp" CASCADE;
+
+-- GenLink create
+CREATE FOREIGN TABLE IF NOT EXISTS "sales"."orderstemp" (
+id,
+name,
+created
+)
+SERVER prod 
+OPTIONS (table_name 'dbo.orders', row_estimate_method 'showplan_all', match_column_names '0');
+
+-- GenUpdate drop
+
+DROP PROCEDURE IF EXISTS "sales"."upd_orders"();
+-- GenUpdate create
+
+CREATE OR REPLACE PROCEDURE "sales"."upd_orders"()
+LANGUAGE plpgsql
+AS $procedure$
+BEGIN
+DELETE
+FROM "sales"."orders"
+USING "sales"."orders" AS d
+LEFT OUTER JOIN "sales"."orderstemp" "orderstemp" ON
+d."id" = "orderstemp"."id"
+WHERE
+"orders"."id" = d."id" 
+AND "orderstemp"."id" IS NULL;
+UPDATE "sales"."orders"
+SET
+"name" = "orderstemp"."name",
+"created" = "orderstemp"."created"
+FROM "sales"."orderstemp" "orderstemp"
+WHERE
+"orders"."id" = "orderstemp"."id"
+AND (
+"orders"."name" <> "orderstemp"."name" OR 
+"orders"."created" <> "orderstemp"."created"
+);
+INSERT INTO "sales"."orders"
+SELECT
+"orderstemp"."id" "id",
+"orderstemp"."name" "name",
+"orderstemp"."created" "created"
+FROM "sales"."orders"
+RIGHT JOIN "sales"."orderstemp" "orderstemp" ON
+"orders"."id" = "orderstemp"."id"
+WHERE "orders"."id" IS NULL;
+END
+$procedure$;
+
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+//########
+// TLS
+//########
+
+// TLS modes, named after the postgres sslmode values
+const (
+	TLSDisable    = "disable"
+	TLSPrefer     = "prefer"
+	TLSRequire    = "require"
+	TLSVerifyCA   = "verify-ca"
+	TLSVerifyFull = "verify-full"
+)
+
+// TLSModes supported TLS modes
+var TLSModes = []string{TLSDisable, TLSPrefer, TLSRequire, TLSVerifyCA, TLSVerifyFull}
+
+// TLS connection encryption settings
+type TLS struct {
+	Mode       string `json:"mode,omitempty"` // disable, prefer, require, verify-ca, verify-full
+	CA         string `json:"ca,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	ServerName string `yaml:"server_name" json:"server_name,omitempty"`
+}
+
+// mode returns the TLS mode, disable when unset
+func (t TLS) mode() string {
+	if t.Mode == "" {
+		return TLSDisable
+	}
+	return t.Mode
+}
+
+// check reports settings the driver cannot honor
+func (t TLS) check(driver string) error {
+	if !slices.Contains(TLSModes, t.mode()) {
+		return fmt.Errorf("tls: unknown mode %s, one of %s", t.Mode, strings.Join(TLSModes, ", "))
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		return fmt.Errorf("tls: cert and key have to be set together")
+	}
+	if driver == "mssql" && t.Cert != "" {
+		return fmt.Errorf("tls: mssql does not support client certificates")
+	}
+	return nil
+}
+
+// pgParams postgres connection parameters, the server name override is
+// applied to the parsed config in OpenDatabase
+func (t TLS) pgParams() url.Values {
+	q := url.Values{}
+	q.Set("sslmode", t.mode())
+	if t.CA != "" {
+		q.Set("sslrootcert", t.CA)
+	}
+	if t.Cert != "" {
+		q.Set("sslcert", t.Cert)
+		q.Set("sslkey", t.Key)
+	}
+	return q
+}
+
+// mssqlParams go-mssqldb connection parameters. The server certificate is
+// always checked against the host name, so verify-ca acts as verify-full.
+func (t TLS) mssqlParams() string {
+	switch t.mode() {
+	case TLSPrefer:
+		return "encrypt=false;TrustServerCertificate=true"
+	case TLSRequire:
+		return "encrypt=true;TrustServerCertificate=true"
+	case TLSVerifyCA, TLSVerifyFull:
+		p := "encrypt=true;TrustServerCertificate=false"
+		if t.CA != "" {
+			p += ";certificate=" + t.CA
+		}
+		if t.ServerName != "" {
+			p += ";hostNameInCertificate=" + t.ServerName
+		}
+		return p
+	}
+	return "encrypt=disable"
+}
+
+// TLSVersion returns the negotiated TLS version of the connection, empty
+// when it is not encrypted. mssql does not expose the version, encrypted
+// connections report "encrypted", and reading it from
+// sys.dm_exec_connections needs the VIEW SERVER STATE permission.
+func (db *Database) TLSVersion(ctx context.Context) (string, error) {
+	var q string
+	switch db.Driver {
+	case "postgres", "pgx":
+		q = "SELECT COALESCE((SELECT version FROM pg_stat_ssl WHERE pid = pg_backend_pid()), '')"
+	case "mssql":
+		q = "SELECT CASE encrypt_option WHEN 'TRUE' THEN 'encrypted' ELSE '' END FROM sys.dm_exec_connections WHERE session_id = @@SPID"
+	default:
+		return "", nil
+	}
+	var v string
+	if err := db.QueryRowxContext(ctx, q).Scan(&v); err != nil {
+		return "", fmt.Errorf("tls version: %w", err)
+	}
+	return v, nil
+}
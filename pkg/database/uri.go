@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//########
+// URIs
+//########
+
+// IsURI reports whether s is a connection URI rather than a host name
+func IsURI(s string) bool {
+	return strings.Contains(s, "://")
+}
+
+// ParseURI parses a postgres://, postgresql://, sqlserver:// or sqlite://
+// connection URI. The URI is kept as is so its query parameters reach the
+// driver.
+func ParseURI(uri string) (Database, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Database{}, fmt.Errorf("parse uri: %s", MaskURI(uri))
+	}
+	db := Database{
+		Hostname: u.Hostname(),
+		Username: u.User.Username(),
+		URI:      uri,
+	}
+	db.Password, _ = u.User.Password()
+	if p := u.Port(); p != "" {
+		if db.Port, err = strconv.Atoi(p); err != nil {
+			return Database{}, fmt.Errorf("parse uri: invalid port %s", p)
+		}
+	}
+
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		db.Driver = "pgx"
+		db.Database = strings.TrimPrefix(u.Path, "/")
+	case "sqlserver":
+		db.Driver = "mssql"
+		db.Database = u.Query().Get("database")
+	case "sqlite", "sqlite3":
+		// sqlite:///abs/path.db or sqlite://rel/path.db
+		db.Driver = "sqlite3"
+		db.Database = u.Host + u.Path
+		db.Hostname = ""
+		db.URI = db.Database
+		if u.RawQuery != "" {
+			db.URI = "file:" + db.Database + "?" + u.RawQuery
+		}
+	default:
+		return Database{}, fmt.Errorf("parse uri: unsupported scheme %s", u.Scheme)
+	}
+	if db.Driver != "sqlite3" && db.Hostname == "" {
+		return Database{}, fmt.Errorf("parse uri: missing host in %s", MaskURI(uri))
+	}
+	return db, nil
+}
+
+var dsnPassword = regexp.MustCompile(`(?i)(password|pwd)=[^;]*`)
+
+// uriPassword password given as a query parameter or an ADO style
+// ;password= part of a connection URI
+var uriPassword = regexp.MustCompile(`(?i)([?&;](?:ssl)?(?:password|pwd)=)[^&;]*`)
+
+// MaskURI hides the password of a connection URI or DSN
+func MaskURI(uri string) string {
+	if IsURI(uri) {
+		if u, err := url.Parse(uri); err == nil {
+			uri = u.Redacted()
+		}
+		return uriPassword.ReplaceAllString(uri, "${1}xxxxx")
+	}
+	return dsnPassword.ReplaceAllString(uri, "${1}=xxxxx")
+}
+
+// LogValue logs the connection without its password
+func (db Database) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("driver", db.Driver),
+		slog.String("hostname", db.Hostname),
+		slog.Int("port", db.Port),
+		slog.String("database", db.Database),
+		slog.String("username", db.Username),
+		slog.String("uri", MaskURI(db.URI)),
+	)
+}
@@ -5,9 +5,6 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
-
-	ec "github.com/ppreeper/dbtools/pkg/errcheck"
 )
 
 //########
@@ -25,9 +22,7 @@ type ViewList struct {
 }
 
 // GetViews returns list of views and definitions
-func (c *Conn) GetViews(schema string, timeout int) ([]ViewList, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+func (c *Conn) GetViews(ctx context.Context, schema string) ([]ViewList, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -40,17 +35,19 @@ func (c *Conn) GetViews(schema string, timeout int) ([]ViewList, error) {
 		FROM INFORMATION_SCHEMA.VIEWS 
 		WHERE TABLE_SCHEMA = ? 
 		ORDER BY TABLE_NAME`
+	default:
+		return nil, unsupported("list views", c.Source.Driver)
 	}
 
 	vv := []ViewList{}
 	if err := c.Source.SelectContext(ctx, &vv, q, schema); err != nil {
-		return nil, fmt.Errorf("select: %w", err)
+		return nil, catalogErr("list views", schema, err)
 	}
 	return vv, nil
 }
 
 // GetViewSchema returns views and definition
-func (c *Conn) GetViewSchema(schema, view string) (View, error) {
+func (c *Conn) GetViewSchema(ctx context.Context, schema, view string) (View, error) {
 	q := ""
 	switch c.Source.Driver {
 	case "postgres", "pgx":
@@ -58,21 +55,23 @@ func (c *Conn) GetViewSchema(schema, view string) (View, error) {
 		FROM INFORMATION_SCHEMA.VIEWS 
 		WHERE TABLE_SCHEMA = $1 AND TABLE_NAME = $2 
 		ORDER BY TABLE_NAME`
-	case "mysql":
+	case "mssql":
 		q += `SELECT TABLE_NAME AS "TABLE_NAME", VIEW_DEFINITION AS "VIEW_DEFINITION"
 		FROM INFORMATION_SCHEMA.VIEWS 
 		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? 
 		ORDER BY TABLE_NAME`
+	default:
+		return View{}, unsupported("get view", c.Source.Driver)
 	}
 	vv := View{}
-	if err := c.Source.Get(&vv, q, schema, view); err != nil {
-		return View{}, fmt.Errorf("select: %w", err)
+	if err := c.Source.GetContext(ctx, &vv, q, schema, view); err != nil {
+		return View{}, catalogErr("get view", objectName(schema, view), err)
 	}
 	return vv, nil
 }
 
 // GetView gets view definition
-func (c *Conn) GetView(d Database, schema string, view View, dbg bool) {
+func (c *Conn) GetView(d Database, schema string, view View, dbg bool) error {
 	fmt.Printf("\n-- VIEW: %s.%s", schema, view.Name)
 	q := ""
 	switch d.Driver {
@@ -89,9 +88,9 @@ func (c *Conn) GetView(d Database, schema string, view View, dbg bool) {
 	} else {
 		t := strings.Replace(view.Name, "/", "_", -1)
 		fname := fmt.Sprintf("%s.%s.%s.VIEW.sql", d.Database, schema, t)
-		f, err := os.Create(fname)
-		ec.CheckErr(err)
-		defer f.Close()
-		f.Write([]byte(q))
+		if err := os.WriteFile(fname, []byte(q), 0o666); err != nil {
+			return fmt.Errorf("view %s: %w", objectName(schema, view.Name), err)
+		}
 	}
+	return nil
 }
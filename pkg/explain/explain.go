@@ -0,0 +1,161 @@
+package explain
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+//########
+// Explain
+//########
+
+// Node normalized execution plan node
+type Node struct {
+	Op         string  `json:"op"`
+	Object     string  `json:"object,omitempty"`
+	Cost       float64 `json:"cost"`
+	Rows       float64 `json:"rows"`
+	ActualRows float64 `json:"actual_rows,omitempty"`
+	Time       float64 `json:"time_ms,omitempty"`
+	Children   []*Node `json:"children,omitempty"`
+}
+
+// Plan execution plan of a query
+type Plan struct {
+	Driver   string `json:"driver"`
+	Analyzed bool   `json:"analyzed"`
+	Root     *Node  `json:"root"`
+	Raw      []byte `json:"-"`
+}
+
+// Ext returns the file extension of the raw plan
+func (p *Plan) Ext() string {
+	if p.Driver == "mssql" {
+		return ".xml"
+	}
+	return ".json"
+}
+
+// Capture fetches and parses the execution plan of stmt, with analyze
+// the statement is executed to collect actual rows and timing
+func Capture(ctx context.Context, db *database.Database, stmt string, analyze bool) (*Plan, error) {
+	var raw []byte
+	var err error
+	switch db.Driver {
+	case "postgres", "pgx":
+		raw, err = capturePostgres(ctx, db, stmt, analyze)
+	case "mssql":
+		raw, err = captureMSSQL(ctx, db, stmt, analyze)
+	default:
+		return nil, fmt.Errorf("explain: unsupported driver %s", db.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	return Parse(db.Driver, raw, analyze)
+}
+
+// Parse parses a raw plan captured from driver
+func Parse(driver string, raw []byte, analyze bool) (*Plan, error) {
+	var root *Node
+	var err error
+	switch driver {
+	case "postgres", "pgx":
+		root, err = parsePostgres(raw)
+	case "mssql":
+		root, err = parseMSSQL(raw)
+	default:
+		return nil, fmt.Errorf("explain: unsupported driver %s", driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	return &Plan{Driver: driver, Analyzed: analyze, Root: root, Raw: raw}, nil
+}
+
+func capturePostgres(ctx context.Context, db *database.Database, stmt string, analyze bool) ([]byte, error) {
+	opts := "FORMAT JSON"
+	if analyze {
+		opts += ", ANALYZE, BUFFERS"
+	}
+	// analyze executes the statement, roll it back so explain has no side effects
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var raw []byte
+	if err := tx.QueryRowContext(ctx, "EXPLAIN ("+opts+") "+stmt).Scan(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func captureMSSQL(ctx context.Context, db *database.Database, stmt string, analyze bool) ([]byte, error) {
+	// SET SHOWPLAN_XML is session scoped, keep every statement on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var q interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	} = conn
+	set := "SHOWPLAN_XML"
+	if analyze {
+		set = "STATISTICS XML"
+		// analyze executes the statement, roll it back so explain has no side effects
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+	if _, err := q.ExecContext(ctx, "SET "+set+" ON"); err != nil {
+		return nil, err
+	}
+	defer q.ExecContext(context.Background(), "SET "+set+" OFF")
+
+	rows, err := q.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// the plan is the last result set, after any query results
+	var raw []byte
+	for {
+		cols, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		isPlan := len(cols) == 1 && strings.Contains(strings.ToLower(cols[0]), "showplan")
+		for rows.Next() {
+			if !isPlan {
+				continue
+			}
+			var s sql.NullString
+			if err := rows.Scan(&s); err != nil {
+				return nil, err
+			}
+			raw = []byte(s.String)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("no plan returned")
+	}
+	return raw, nil
+}
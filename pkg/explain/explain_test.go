@@ -0,0 +1,88 @@
+package explain
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const pgPlan = `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 35.5, "Plan Rows": 100,
+ "Actual Rows": 98, "Actual Total Time": 1.5, "Actual Loops": 1,
+ "Plans": [
+  {"Node Type": "Seq Scan", "Relation Name": "orders", "Schema": "public", "Total Cost": 20, "Plan Rows": 1000},
+  {"Node Type": "Hash", "Total Cost": 10, "Plan Rows": 10, "Plans": [
+   {"Node Type": "Index Scan", "Relation Name": "customers", "Index Name": "customers_pkey", "Total Cost": 8, "Plan Rows": 10}
+  ]}
+ ]}, "Planning Time": 0.1}]`
+
+const mssqlPlan = `<?xml version="1.0" encoding="utf-16"?>
+<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.5">
+ <BatchSequence><Batch><Statements><StmtSimple StatementText="select 1">
+  <QueryPlan>
+   <RelOp NodeId="0" PhysicalOp="Hash Match" LogicalOp="Inner Join" EstimateRows="100" EstimatedTotalSubtreeCost="0.5">
+    <RunTimeInformation><RunTimeCountersPerThread Thread="0" ActualRows="98" ActualElapsedms="3"/></RunTimeInformation>
+    <Hash>
+     <RelOp NodeId="1" PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="1000" EstimatedTotalSubtreeCost="0.2">
+      <IndexScan><Object Database="[db]" Schema="[dbo]" Table="[orders]" Index="[PK_orders]"/></IndexScan>
+     </RelOp>
+     <RelOp NodeId="2" PhysicalOp="Table Scan" LogicalOp="Table Scan" EstimateRows="10" EstimatedTotalSubtreeCost="0.1">
+      <TableScan><Object Database="[db]" Schema="[dbo]" Table="[customers]"/></TableScan>
+     </RelOp>
+    </Hash>
+   </RelOp>
+  </QueryPlan>
+ </StmtSimple></Statements></Batch></BatchSequence>
+</ShowPlanXML>`
+
+func TestParsePostgres(t *testing.T) {
+	p, err := Parse("pgx", []byte(pgPlan), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root.Op != "Hash Join" || len(p.Root.Children) != 2 || p.Root.ActualRows != 98 {
+		t.Errorf("unexpected root: %+v", p.Root)
+	}
+	if got := p.Root.Children[0].Object; got != "public.orders" {
+		t.Errorf("object = %q", got)
+	}
+	if got := p.Root.Children[1].Children[0].Object; got != "customers using customers_pkey" {
+		t.Errorf("object = %q", got)
+	}
+}
+
+func TestParseMSSQL(t *testing.T) {
+	p, err := Parse("mssql", []byte(mssqlPlan), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root.Op != "Hash Match (Inner Join)" || p.Root.ActualRows != 98 || p.Root.Time != 3 {
+		t.Errorf("unexpected root: %+v", p.Root)
+	}
+	if len(p.Root.Children) != 2 {
+		t.Fatalf("children = %d", len(p.Root.Children))
+	}
+	if got := p.Root.Children[0].Object; got != "dbo.orders using PK_orders" {
+		t.Errorf("object = %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := Parse("pgx", []byte(pgPlan), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse("mssql", []byte(mssqlPlan), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	Diff(&buf, a, b, "a", "b")
+	out := buf.String()
+	t.Log(out)
+	if !strings.Contains(out, "~ Hash Join -> Hash Match (Inner Join)") {
+		t.Error("changed root not marked")
+	}
+	if !strings.Contains(out, "- ") {
+		t.Error("missing node not marked")
+	}
+}
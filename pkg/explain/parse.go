@@ -0,0 +1,170 @@
+package explain
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// pgNode node of a postgres FORMAT JSON plan
+type pgNode struct {
+	NodeType        string    `json:"Node Type"`
+	RelationName    string    `json:"Relation Name"`
+	Schema          string    `json:"Schema"`
+	IndexName       string    `json:"Index Name"`
+	CTEName         string    `json:"CTE Name"`
+	FunctionName    string    `json:"Function Name"`
+	TotalCost       float64   `json:"Total Cost"`
+	PlanRows        float64   `json:"Plan Rows"`
+	ActualRows      float64   `json:"Actual Rows"`
+	ActualTotalTime float64   `json:"Actual Total Time"`
+	ActualLoops     float64   `json:"Actual Loops"`
+	Plans           []*pgNode `json:"Plans"`
+}
+
+func parsePostgres(raw []byte) (*Node, error) {
+	var plans []struct {
+		Plan *pgNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("empty plan")
+	}
+	return plans[0].Plan.node(), nil
+}
+
+func (p *pgNode) node() *Node {
+	n := &Node{
+		Op:   p.NodeType,
+		Cost: p.TotalCost,
+		Rows: p.PlanRows,
+	}
+	switch {
+	case p.RelationName != "" && p.Schema != "":
+		n.Object = p.Schema + "." + p.RelationName
+	case p.RelationName != "":
+		n.Object = p.RelationName
+	case p.CTEName != "":
+		n.Object = p.CTEName
+	case p.FunctionName != "":
+		n.Object = p.FunctionName
+	}
+	if p.IndexName != "" {
+		n.Object += " using " + p.IndexName
+	}
+	// actual values are per loop
+	loops := max(p.ActualLoops, 1)
+	n.ActualRows = p.ActualRows * loops
+	n.Time = p.ActualTotalTime * loops
+	for _, c := range p.Plans {
+		n.Children = append(n.Children, c.node())
+	}
+	return n
+}
+
+// xmlElem generic showplan xml element
+type xmlElem struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlElem  `xml:",any"`
+}
+
+func (e *xmlElem) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (e *xmlElem) float(name string) float64 {
+	f, _ := strconv.ParseFloat(e.attr(name), 64)
+	return f
+}
+
+func parseMSSQL(raw []byte) (*Node, error) {
+	var root xmlElem
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	// the driver has already decoded the utf-16 the plan declares
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	var stmts []*Node
+	var walk func(e *xmlElem)
+	walk = func(e *xmlElem) {
+		if e.XMLName.Local == "QueryPlan" {
+			for i := range e.Children {
+				if e.Children[i].XMLName.Local == "RelOp" {
+					stmts = append(stmts, relOp(&e.Children[i]))
+				}
+			}
+			return
+		}
+		for i := range e.Children {
+			walk(&e.Children[i])
+		}
+	}
+	walk(&root)
+	switch len(stmts) {
+	case 0:
+		return nil, fmt.Errorf("empty plan")
+	case 1:
+		return stmts[0], nil
+	default:
+		n := &Node{Op: "Batch", Children: stmts}
+		for _, s := range stmts {
+			n.Cost += s.Cost
+		}
+		return n, nil
+	}
+}
+
+// relOp converts a RelOp element and the RelOps nested below it
+func relOp(e *xmlElem) *Node {
+	n := &Node{
+		Op:   e.attr("PhysicalOp"),
+		Cost: e.float("EstimatedTotalSubtreeCost"),
+		Rows: e.float("EstimateRows"),
+	}
+	if logical := e.attr("LogicalOp"); logical != "" && logical != n.Op {
+		n.Op += " (" + logical + ")"
+	}
+	var walk func(e *xmlElem)
+	walk = func(e *xmlElem) {
+		for i := range e.Children {
+			c := &e.Children[i]
+			switch c.XMLName.Local {
+			case "RelOp":
+				n.Children = append(n.Children, relOp(c))
+				continue
+			case "Object":
+				if n.Object == "" {
+					n.Object = trimBrackets(c.attr("Schema")) + "." + trimBrackets(c.attr("Table"))
+					if idx := c.attr("Index"); idx != "" {
+						n.Object += " using " + trimBrackets(idx)
+					}
+				}
+			case "RunTimeCountersPerThread":
+				n.ActualRows += c.float("ActualRows")
+				n.Time = max(n.Time, c.float("ActualElapsedms"))
+			}
+			walk(c)
+		}
+	}
+	walk(e)
+	return n
+}
+
+func trimBrackets(s string) string {
+	if len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package explain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Render prints the plan as an indented tree
+func Render(w io.Writer, p *Plan) {
+	renderNode(w, p.Root, p.Analyzed, "", "")
+}
+
+func renderNode(w io.Writer, n *Node, analyzed bool, prefix, childPrefix string) {
+	fmt.Fprintf(w, "%s%s  %s\n", prefix, n.label(), n.stats(analyzed))
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			renderNode(w, c, analyzed, childPrefix+"└─ ", childPrefix+"   ")
+		} else {
+			renderNode(w, c, analyzed, childPrefix+"├─ ", childPrefix+"│  ")
+		}
+	}
+}
+
+func (n *Node) label() string {
+	if n.Object != "" {
+		return n.Op + " on " + n.Object
+	}
+	return n.Op
+}
+
+func (n *Node) stats(analyzed bool) string {
+	s := "cost=" + num(n.Cost) + " rows=" + num(n.Rows)
+	if analyzed {
+		s += " actual=" + num(n.ActualRows) + " time=" + num(n.Time) + "ms"
+	}
+	return s
+}
+
+// Diff prints both plans as one tree, nodes are matched by position.
+// Lines are marked "=" same operation, "~" changed operation, "-" only
+// in a and "+" only in b
+func Diff(w io.Writer, a, b *Plan, aName, bName string) {
+	fmt.Fprintf(w, "--- %s (%s)\n+++ %s (%s)\n", aName, a.Driver, bName, b.Driver)
+	diffNode(w, a.Root, b.Root, a.Analyzed && b.Analyzed, "", "")
+}
+
+func diffNode(w io.Writer, a, b *Node, analyzed bool, prefix, childPrefix string) {
+	switch {
+	case b == nil:
+		fmt.Fprintf(w, "- %s%s  %s\n", prefix, a.label(), a.stats(analyzed))
+	case a == nil:
+		fmt.Fprintf(w, "+ %s%s  %s\n", prefix, b.label(), b.stats(analyzed))
+	case a.label() == b.label():
+		fmt.Fprintf(w, "= %s%s  %s\n", prefix, a.label(), diffStats(a, b, analyzed))
+	default:
+		fmt.Fprintf(w, "~ %s%s -> %s  %s\n", prefix, a.label(), b.label(), diffStats(a, b, analyzed))
+	}
+
+	var ac, bc []*Node
+	if a != nil {
+		ac = a.Children
+	}
+	if b != nil {
+		bc = b.Children
+	}
+	count := max(len(ac), len(bc))
+	for i := 0; i < count; i++ {
+		var an, bn *Node
+		if i < len(ac) {
+			an = ac[i]
+		}
+		if i < len(bc) {
+			bn = bc[i]
+		}
+		if i == count-1 {
+			diffNode(w, an, bn, analyzed, childPrefix+"└─ ", childPrefix+"   ")
+		} else {
+			diffNode(w, an, bn, analyzed, childPrefix+"├─ ", childPrefix+"│  ")
+		}
+	}
+}
+
+func diffStats(a, b *Node, analyzed bool) string {
+	s := "cost=" + change(a.Cost, b.Cost) + " rows=" + change(a.Rows, b.Rows)
+	if analyzed {
+		s += " actual=" + change(a.ActualRows, b.ActualRows) + " time=" + change(a.Time, b.Time) + "ms"
+	}
+	return s
+}
+
+func change(a, b float64) string {
+	if a == b {
+		return num(a)
+	}
+	s := num(a) + "->" + num(b)
+	if a != 0 {
+		s += fmt.Sprintf("(%+.0f%%)", (b-a)/a*100)
+	}
+	return s
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,210 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//########
+// Archive
+//########
+
+// IsArchive reports whether fn names a .tar.gz, .tgz or .zip archive
+func IsArchive(fn string) bool {
+	return isTarGz(fn) || strings.HasSuffix(strings.ToLower(fn), ".zip")
+}
+
+func isTarGz(fn string) bool {
+	fn = strings.ToLower(fn)
+	return strings.HasSuffix(fn, ".tar.gz") || strings.HasSuffix(fn, ".tgz")
+}
+
+// WriteArchive packs the manifest and its files into a .tar.gz, .tgz or
+// .zip archive. The entries get a fixed time so the same objects make the
+// same archive.
+func (m *Manifest) WriteArchive(fn string) (err error) {
+	if !IsArchive(fn) {
+		return fmt.Errorf("write archive %s: expected a .tar.gz, .tgz or .zip file", fn)
+	}
+	data, err := m.JSON()
+	if err != nil {
+		return err
+	}
+	w, err := os.Create(fn)
+	if err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	defer func() {
+		if cerr := w.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("write archive: %w", cerr)
+		}
+		if err != nil {
+			os.Remove(fn)
+		}
+	}()
+	if isTarGz(fn) {
+		return m.writeTarGz(w, data)
+	}
+	return m.writeZip(w, data)
+}
+
+func (m *Manifest) writeTarGz(w io.Writer, manifest []byte) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	add := func(name string, size int64, r io.Reader) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: time.Unix(0, 0), Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := io.Copy(tw, r)
+		return err
+	}
+	if err := add(FileName, int64(len(manifest)), strings.NewReader(string(manifest))); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	for _, f := range m.Files {
+		r, size, err := f.open()
+		if err != nil {
+			return err
+		}
+		err = add(f.Path, size, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("write archive %s: %w", f.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+func (m *Manifest) writeZip(w io.Writer, manifest []byte) error {
+	zw := zip.NewWriter(w)
+	add := func(name string, r io.Reader) error {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, r)
+		return err
+	}
+	if err := add(FileName, strings.NewReader(string(manifest))); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	for _, f := range m.Files {
+		r, _, err := f.open()
+		if err != nil {
+			return err
+		}
+		err = add(f.Path, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("write archive %s: %w", f.Path, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+// Extract unpacks a .tar.gz, .tgz or .zip archive into dir. Entries that
+// would land outside dir are an error.
+func Extract(fn, dir string) error {
+	if isTarGz(fn) {
+		return extractTarGz(fn, dir)
+	}
+	if IsArchive(fn) {
+		return extractZip(fn, dir)
+	}
+	return fmt.Errorf("extract %s: expected a .tar.gz, .tgz or .zip file", fn)
+}
+
+func extractTarGz(fn, dir string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("extract %s: %w", fn, err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", fn, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := extractFile(dir, hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(fn, dir string) error {
+	zr, err := zip.OpenReader(fn)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", zf.Name, err)
+		}
+		err = extractFile(dir, zf.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the archive entry name under dir
+func extractFile(dir, name string, r io.Reader) error {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("extract: entry %s is outside the archive", name)
+	}
+	fn := filepath.Join(dir, filepath.FromSlash(clean))
+	if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	w, err := os.Create(fn)
+	if err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("extract %s: %w", name, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("extract: %w", err)
+	}
+	return nil
+}
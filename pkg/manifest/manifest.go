@@ -0,0 +1,278 @@
+package manifest
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+//########
+// Manifest
+//########
+
+// FileName name of the manifest in the output tree
+const FileName = "manifest.json"
+
+// kinds object kinds in replay order and the directory of each
+var kinds = []struct{ kind, dir string }{
+	{"table", "tables"},
+	{"foreign table", "foreign_tables"},
+	{"data", "data"},
+	{"view", "views"},
+	{"routine", "routines"},
+	{"update", "updates"},
+	{"index", "indexes"},
+}
+
+// Object generated sql or exported rows of a database object
+type Object struct {
+	Schema     string
+	Kind       string // table, foreign table, data, view, routine, update or index
+	Name       string
+	SQL        string
+	File       string   // local file holding the content instead of SQL
+	Dependents []string // views of the schema that depend on the object
+}
+
+// File manifest entry of an object file
+type File struct {
+	Path   string `json:"path"` // slash separated, relative to the tree
+	Schema string `json:"schema"`
+	Kind   string `json:"kind"`
+	Object string `json:"object"`
+	SHA256 string `json:"sha256"`
+
+	sql  string
+	file string
+}
+
+// Manifest files of an output tree in replay order
+type Manifest struct {
+	Source string `json:"source"`
+	Driver string `json:"driver"` // sql dialect of the files
+	Files  []File `json:"files"`
+}
+
+// Build orders the objects for replay: by schema, tables before the views,
+// routines and indexes, and views after the views they depend on. The files
+// are numbered in that order in a directory per schema and kind.
+func Build(source, driver string, objs []Object) (*Manifest, error) {
+	objs = slices.Clone(objs)
+	// the dependents may be direct only, as on mssql: following them
+	// recursively a view depends on fewer views than any view that depends
+	// on it
+	deps := make(map[[2]string][]string)
+	for _, o := range objs {
+		k := [2]string{o.Schema, o.Name}
+		deps[k] = append(deps[k], o.Dependents...)
+	}
+	depth := make(map[[2]string]int)
+	for _, o := range objs {
+		seen := map[string]bool{o.Name: true}
+		next := slices.Clone(o.Dependents)
+		for len(next) > 0 {
+			d := next[len(next)-1]
+			next = next[:len(next)-1]
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			depth[[2]string{o.Schema, d}]++
+			next = append(next, deps[[2]string{o.Schema, d}]...)
+		}
+	}
+	slices.SortStableFunc(objs, func(a, b Object) int {
+		return cmp.Or(strings.Compare(a.Schema, b.Schema), cmp.Compare(kindRank(a.Kind), kindRank(b.Kind)),
+			cmp.Compare(depth[[2]string{a.Schema, a.Name}], depth[[2]string{b.Schema, b.Name}]),
+			strings.Compare(a.Name, b.Name))
+	})
+
+	m := &Manifest{Source: source, Driver: driver, Files: []File{}}
+	seq := make(map[string]int)
+	for _, o := range objs {
+		dir := path.Join(safeName(o.Schema), kindDir(o.Kind))
+		seq[dir]++
+		ext := ".sql"
+		if o.Kind == "data" {
+			ext = ".jsonl"
+		}
+		f := File{
+			Path:   path.Join(dir, fmt.Sprintf("%04d_%s%s", seq[dir], safeName(o.Name), ext)),
+			Schema: o.Schema,
+			Kind:   o.Kind,
+			Object: o.Name,
+			sql:    o.SQL,
+			file:   o.File,
+		}
+		r, _, err := f.open()
+		if err != nil {
+			return nil, err
+		}
+		f.SHA256, err = checksum(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, f)
+	}
+	return m, nil
+}
+
+// open returns the content of a built file and its size
+func (f File) open() (io.ReadCloser, int64, error) {
+	if f.file == "" {
+		return io.NopCloser(strings.NewReader(f.sql)), int64(len(f.sql)), nil
+	}
+	r, err := os.Open(f.file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("manifest: %w", err)
+	}
+	st, err := r.Stat()
+	if err != nil {
+		r.Close()
+		return nil, 0, fmt.Errorf("manifest: %w", err)
+	}
+	return r, st.Size(), nil
+}
+
+// checksum hex sha256 of the content of r
+func checksum(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("manifest: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// kindRank position of kind in the replay order
+func kindRank(kind string) int {
+	i := slices.IndexFunc(kinds, func(k struct{ kind, dir string }) bool { return k.kind == kind })
+	if i < 0 {
+		return len(kinds)
+	}
+	return i
+}
+
+// kindDir directory of the objects of kind
+func kindDir(kind string) string {
+	if i := kindRank(kind); i < len(kinds) {
+		return kinds[i].dir
+	}
+	return safeName(kind)
+}
+
+// safeName name usable as a single path element
+func safeName(name string) string {
+	return strings.NewReplacer("/", "_", `\`, "_", "..", "_").Replace(name)
+}
+
+// WriteDir writes the files and the manifest to dir. Files of a previous
+// manifest in dir that are no longer listed are removed.
+func (m *Manifest) WriteDir(dir string) error {
+	old, err := Load(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	keep := make(map[string]bool)
+	for _, f := range m.Files {
+		fn := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			return fmt.Errorf("write manifest: %w", err)
+		}
+		if err := writeFile(fn, f); err != nil {
+			return err
+		}
+		keep[f.Path] = true
+	}
+	if old != nil {
+		for _, f := range old.Files {
+			if !keep[f.Path] {
+				if err := os.Remove(filepath.Join(dir, filepath.FromSlash(f.Path))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return fmt.Errorf("write manifest: %w", err)
+				}
+			}
+		}
+	}
+	data, err := m.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), data, 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeFile writes the content of f to fn
+func writeFile(fn string, f File) error {
+	r, _, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(fn)
+	if err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// JSON the manifest as indented JSON
+func (m *Manifest) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Load reads the manifest of the tree in dir
+func Load(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, fmt.Errorf("load manifest: %w", err)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("load manifest %s: %w", dir, err)
+	}
+	return m, nil
+}
+
+// Verify checks every file of the manifest in dir against its checksum
+func (m *Manifest) Verify(dir string) error {
+	var errs []error
+	for _, f := range m.Files {
+		r, err := os.Open(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sum, err := checksum(r)
+		r.Close()
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case sum != f.SHA256:
+			errs = append(errs, fmt.Errorf("%s: checksum mismatch", f.Path))
+		}
+	}
+	return errors.Join(errs...)
+}
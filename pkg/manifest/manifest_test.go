@@ -0,0 +1,134 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	objs := []Object{
+		{Schema: "sales", Kind: "view", Name: "a_top", SQL: "CREATE VIEW a_top"},
+		{Schema: "sales", Kind: "index", Name: "orders_id_idx", SQL: "CREATE INDEX"},
+		{Schema: "sales", Kind: "view", Name: "z_base", SQL: "CREATE VIEW z_base", Dependents: []string{"m_mid", "a_top"}},
+		{Schema: "sales", Kind: "view", Name: "m_mid", SQL: "CREATE VIEW m_mid", Dependents: []string{"a_top"}},
+		{Schema: "sales", Kind: "table", Name: "orders", SQL: "CREATE TABLE"},
+		{Schema: "hr", Kind: "table", Name: "staff/old", SQL: "CREATE TABLE"},
+	}
+	m, err := Build("prod", "pgx", objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range m.Files {
+		got = append(got, f.Path)
+	}
+	want := []string{
+		"hr/tables/0001_staff_old.sql",
+		"sales/tables/0001_orders.sql",
+		"sales/views/0001_z_base.sql",
+		"sales/views/0002_m_mid.sql",
+		"sales/views/0003_a_top.sql",
+		"sales/indexes/0001_orders_id_idx.sql",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("paths\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// mssql lists the direct dependents only
+	objs[2].Dependents = []string{"m_mid"}
+	m, err = Build("prod", "mssql", objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	for _, f := range m.Files {
+		got = append(got, f.Path)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("direct dependents paths\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Build("prod", "pgx", []Object{
+		{Schema: "s", Kind: "table", Name: "a", SQL: "CREATE TABLE a ();\n"},
+		{Schema: "s", Kind: "table", Name: "b", SQL: "CREATE TABLE b ();\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	m, err = Build("prod", "pgx", []Object{{Schema: "s", Kind: "table", Name: "b", SQL: "CREATE TABLE b ();\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "s", "tables", "0002_b.sql")); err == nil {
+		t.Error("stale file of the previous manifest kept")
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Files) != 1 || loaded.Files[0].Path != "s/tables/0001_b.sql" || loaded.Source != "prod" {
+		t.Errorf("loaded %+v", loaded)
+	}
+	if err := loaded.Verify(dir); err != nil {
+		t.Error(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "s", "tables", "0001_b.sql"), []byte("DROP TABLE b;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Verify(dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	tmp := t.TempDir()
+	rows := filepath.Join(tmp, "rows.jsonl")
+	if err := os.WriteFile(rows, []byte("[\"id\"]\n[\"1\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Build("prod", "pgx", []Object{
+		{Schema: "s", Kind: "table", Name: "a", SQL: "CREATE TABLE a ();\n"},
+		{Schema: "s", Kind: "data", Name: "a", File: rows},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Files[1].Path != "s/data/0001_a.jsonl" {
+		t.Errorf("data path %s", m.Files[1].Path)
+	}
+	for _, fn := range []string{"out.tar.gz", "out.zip"} {
+		archive := filepath.Join(tmp, fn)
+		if err := m.WriteArchive(archive); err != nil {
+			t.Fatal(err)
+		}
+		dir := filepath.Join(tmp, fn+".d")
+		if err := Extract(archive, dir); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded.Files) != 2 {
+			t.Errorf("%s: %d files", fn, len(loaded.Files))
+		}
+		if err := loaded.Verify(dir); err != nil {
+			t.Errorf("%s: %v", fn, err)
+		}
+	}
+	if err := extractFile(tmp, "../evil.sql", strings.NewReader("")); err == nil {
+		t.Error("entry outside the archive: expected error")
+	}
+}
@@ -0,0 +1,284 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+//########
+// Migrations
+//########
+
+// Table history table of the applied migrations
+const Table = "dbtools_migrations"
+
+// NoTransaction first line of a migration file whose statements cannot run
+// in a transaction, they run one at a time instead
+const NoTransaction = "-- dbtools:no-transaction"
+
+// fileName <version>_<name>.up.sql or <version>_<name>.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration numbered pair of up and down scripts
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // empty when the migration cannot be reverted
+	Checksum string // sha256 of the up script
+}
+
+// File base name of the migration files, without .up.sql
+func (m Migration) File() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Record history table row of an applied migration
+type Record struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Load reads the migrations of dir in version order
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: %s: %w", e.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("load migrations: %w", err)
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("load migrations: version %d used by %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+			sum := sha256.Sum256(data)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(data)
+		}
+	}
+	var migs []Migration
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("load migrations: %s has no up script", m.File())
+		}
+		migs = append(migs, *m)
+	}
+	slices.SortFunc(migs, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migs, nil
+}
+
+// Next version of a new migration in dir, one after the highest
+func Next(dir string) (int64, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return 1, nil
+	}
+	migs, err := Load(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(migs) == 0 {
+		return 1, nil
+	}
+	return migs[len(migs)-1].Version + 1, nil
+}
+
+// unsafeName characters replaced in a migration name
+var unsafeName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Write adds the next migration named name to dir and returns its up file
+func Write(dir, name, up, down string) (string, error) {
+	version, err := Next(dir)
+	if err != nil {
+		return "", err
+	}
+	name = strings.Trim(unsafeName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	m := Migration{Version: version, Name: cmp.Or(name, "migration")}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("write migration: %w", err)
+	}
+	fn := filepath.Join(dir, m.File()+".up.sql")
+	if err := os.WriteFile(fn, []byte(up), 0o644); err != nil {
+		return "", fmt.Errorf("write migration: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, m.File()+".down.sql"), []byte(down), 0o644); err != nil {
+		return "", fmt.Errorf("write migration: %w", err)
+	}
+	return fn, nil
+}
+
+//########
+// History
+//########
+
+// Init creates the history table when it does not exist
+func Init(ctx context.Context, db *database.Database) error {
+	var q string
+	switch db.Driver {
+	case "mssql":
+		q = `IF OBJECT_ID('` + Table + `', 'U') IS NULL CREATE TABLE ` + Table + ` (
+version BIGINT NOT NULL PRIMARY KEY,
+name NVARCHAR(255) NOT NULL,
+checksum CHAR(64) NOT NULL,
+applied_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME()
+)`
+	case "postgres", "pgx":
+		q = `CREATE TABLE IF NOT EXISTS ` + Table + ` (
+version BIGINT NOT NULL PRIMARY KEY,
+name VARCHAR(255) NOT NULL,
+checksum CHAR(64) NOT NULL,
+applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+	default:
+		q = `CREATE TABLE IF NOT EXISTS ` + Table + ` (
+version BIGINT NOT NULL PRIMARY KEY,
+name VARCHAR(255) NOT NULL,
+checksum CHAR(64) NOT NULL,
+applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+	}
+	_, _, err := db.ExecDDL(ctx, Table, q)
+	return err
+}
+
+// History returns the applied migrations in version order, none when the
+// history table does not exist yet
+func History(ctx context.Context, db *database.Database) ([]Record, error) {
+	var q string
+	switch db.Driver {
+	case "mssql":
+		q = `SELECT count(*) FROM sys.tables WHERE object_id = OBJECT_ID('` + Table + `', 'U')`
+	case "postgres", "pgx":
+		q = `SELECT count(*) FROM pg_catalog.pg_class WHERE oid = to_regclass('` + Table + `')`
+	default:
+		q = `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = '` + Table + `'`
+	}
+	var n int
+	if err := db.GetContext(ctx, &n, q); err != nil {
+		return nil, &database.Error{Kind: database.ErrCatalog, Op: "read migrations", Object: Table, Err: err}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	var rr []Record
+	q = `SELECT version, name, checksum, applied_at FROM ` + Table + ` ORDER BY version`
+	if err := db.SelectContext(ctx, &rr, q); err != nil {
+		return nil, &database.Error{Kind: database.ErrCatalog, Op: "read migrations", Object: Table, Err: err}
+	}
+	return rr, nil
+}
+
+// Migration states
+const (
+	Pending  = "pending"
+	Applied  = "applied"
+	Modified = "modified" // applied, the up script changed since
+	Missing  = "missing"  // applied, the files are gone
+)
+
+// State migration and its history record, either may be missing
+type State struct {
+	Migration
+	Record *Record
+}
+
+// Status state of the migration
+func (s State) Status() string {
+	switch {
+	case s.Record == nil:
+		return Pending
+	case s.Checksum == "":
+		return Missing
+	case s.Checksum != s.Record.Checksum:
+		return Modified
+	}
+	return Applied
+}
+
+// Status merges the migrations and the history in version order
+func Status(migs []Migration, hist []Record) []State {
+	var ss []State
+	for _, m := range migs {
+		ss = append(ss, State{Migration: m})
+	}
+	for _, r := range hist {
+		i := slices.IndexFunc(ss, func(s State) bool { return s.Version == r.Version })
+		if i < 0 {
+			ss = append(ss, State{Migration: Migration{Version: r.Version, Name: r.Name}})
+			i = len(ss) - 1
+		}
+		ss[i].Record = &r
+	}
+	slices.SortFunc(ss, func(a, b State) int { return cmp.Compare(a.Version, b.Version) })
+	return ss
+}
+
+//########
+// Run
+//########
+
+// Up runs the up script of m and records it, in one transaction unless the
+// script starts with NoTransaction
+func Up(ctx context.Context, db *database.Database, m Migration) error {
+	record := fmt.Sprintf(`INSERT INTO %s (version, name, checksum) VALUES (%d, '%s', '%s')`,
+		Table, m.Version, strings.ReplaceAll(m.Name, "'", "''"), m.Checksum)
+	return run(ctx, db, m.File()+".up.sql", m.Up, record)
+}
+
+// Down runs the down script of m and removes its record, in one transaction
+// unless the script starts with NoTransaction
+func Down(ctx context.Context, db *database.Database, m Migration) error {
+	if strings.TrimSpace(m.Down) == "" {
+		return fmt.Errorf("%s: no down script", m.File())
+	}
+	record := fmt.Sprintf(`DELETE FROM %s WHERE version = %d`, Table, m.Version)
+	return run(ctx, db, m.File()+".down.sql", m.Down, record)
+}
+
+// run executes the batches of script and then the history statement
+func run(ctx context.Context, db *database.Database, file, script, record string) error {
+	stmts := database.SplitBatches(db.Driver, script)
+	if strings.HasPrefix(script, NoTransaction) {
+		if n, _, err := db.ExecEach(ctx, file, stmts...); err != nil {
+			return fmt.Errorf("batch %d: %w", n+1, err)
+		}
+		_, _, err := db.ExecDDL(ctx, file, record)
+		return err
+	}
+	if n, _, err := db.ExecDDL(ctx, file, append(stmts, record)...); err != nil {
+		return fmt.Errorf("batch %d: %w", n+1, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+	_ "modernc.org/sqlite" // the tests run on sqlite whatever the build tags
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := Write(dir, "Create Orders", "CREATE TABLE orders (id INTEGER);\n", "DROP TABLE orders;\n"); err != nil {
+		t.Fatal(err)
+	}
+	fn, err := Write(dir, "add note", "ALTER TABLE orders ADD note TEXT;\nINSERT INTO orders VALUES (1, 'a;b');\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(fn) != "0002_add_note.up.sql" {
+		t.Errorf("file %s", fn)
+	}
+	migs, err := Load(dir)
+	if err != nil || len(migs) != 2 || migs[0].Name != "create_orders" {
+		t.Fatalf("load %+v, %v", migs, err)
+	}
+
+	db, err := database.OpenDatabase(ctx, database.Database{Driver: "sqlite3", URI: filepath.Join(t.TempDir(), "m.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if hist, err := History(ctx, db); err != nil || len(hist) != 0 {
+		t.Fatalf("history before init %+v, %v", hist, err)
+	}
+	if err := Init(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migs {
+		if err := Up(ctx, db, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hist, err := History(ctx, db)
+	if err != nil || len(hist) != 2 || hist[1].Checksum != migs[1].Checksum || hist[1].AppliedAt.IsZero() {
+		t.Fatalf("history %+v, %v", hist, err)
+	}
+
+	// a changed up script shows as modified, a down needs a down script
+	if err := os.WriteFile(fn, []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	migs, _ = Load(dir)
+	ss := Status(migs, hist)
+	if ss[0].Status() != Applied || ss[1].Status() != Modified {
+		t.Errorf("status %s %s", ss[0].Status(), ss[1].Status())
+	}
+	if err := Down(ctx, db, migs[1]); err == nil {
+		t.Error("down without a down script: expected error")
+	}
+
+	// a failed up leaves neither its changes nor a record
+	bad := Migration{Version: 3, Name: "bad", Up: "CREATE TABLE t (x INTEGER);\nSELECT * FROM nope;", Checksum: "x"}
+	if err := Up(ctx, db, bad); err == nil {
+		t.Error("bad migration: expected error")
+	}
+	if hist, _ := History(ctx, db); len(hist) != 2 {
+		t.Errorf("history after failed up %d", len(hist))
+	}
+
+	if err := Down(ctx, db, migs[0]); err != nil {
+		t.Fatal(err)
+	}
+	hist, _ = History(ctx, db)
+	ss = Status(migs, hist)
+	if ss[0].Status() != Pending || ss[1].Status() != Modified {
+		t.Errorf("status after down %s %s", ss[0].Status(), ss[1].Status())
+	}
+}
@@ -0,0 +1,171 @@
+package querylib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+//########
+// Query Library
+//########
+
+// Ext file extension of saved queries
+const Ext = ".sql"
+
+// Query saved query with its front-matter
+type Query struct {
+	Name        string  `yaml:"-" json:"name"`
+	Path        string  `yaml:"-" json:"path"`
+	Description string  `yaml:"description" json:"description,omitempty"`
+	Host        string  `yaml:"host" json:"host,omitempty"`
+	Format      string  `yaml:"format" json:"format,omitempty"`
+	Params      []Param `yaml:"params" json:"params,omitempty"`
+	SQL         string  `yaml:"-" json:"sql"`
+}
+
+// Param query parameter declared in the front-matter
+type Param struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Default     string `yaml:"default" json:"default,omitempty"`
+	Required    bool   `yaml:"required" json:"required,omitempty"`
+}
+
+// Dir returns the query library directory next to the config file
+func Dir(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "queries")
+}
+
+// List returns all saved queries in dir sorted by name, none when dir does
+// not exist
+func List(dir string) ([]Query, error) {
+	var qq []Query
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return qq, nil
+	}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != Ext {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		q, err := ReadFile(path)
+		if err != nil {
+			return err
+		}
+		q.Name = filepath.ToSlash(strings.TrimSuffix(rel, Ext))
+		qq = append(qq, q)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list queries: %w", err)
+	}
+	sort.Slice(qq, func(i, j int) bool { return qq[i].Name < qq[j].Name })
+	return qq, nil
+}
+
+// Load returns the saved query name from dir, the name cannot leave dir
+func Load(dir, name string) (Query, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return Query{}, fmt.Errorf("query %s: not a name in the query library", name)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name)+Ext)
+	q, err := ReadFile(path)
+	if err != nil {
+		return Query{}, err
+	}
+	q.Name = name
+	return q, nil
+}
+
+// ReadFile reads and parses a saved query file
+func ReadFile(path string) (Query, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Query{}, fmt.Errorf("read query: %w", err)
+	}
+	q, err := Parse(data)
+	if err != nil {
+		return Query{}, fmt.Errorf("%s: %w", path, err)
+	}
+	q.Path = path
+	q.Name = strings.TrimSuffix(filepath.Base(path), Ext)
+	return q, nil
+}
+
+// Parse splits the yaml front-matter, delimited by "---" lines, from the query text
+func Parse(data []byte) (Query, error) {
+	q := Query{}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if strings.HasPrefix(text, "---\n") {
+		front, body, found := strings.Cut(text[4:], "\n---\n")
+		if !found {
+			return Query{}, fmt.Errorf("front-matter not terminated")
+		}
+		if err := yaml.Unmarshal([]byte(front), &q); err != nil {
+			return Query{}, fmt.Errorf("front-matter: %w", err)
+		}
+		text = body
+	}
+	q.SQL = strings.TrimSpace(text)
+	if q.SQL == "" {
+		return Query{}, fmt.Errorf("empty query")
+	}
+	return q, nil
+}
+
+// Render expands the query template with params, applying the defaults of
+// absent params and checking required params. A param given as empty stays
+// empty.
+func (q Query) Render(params map[string]string) (string, error) {
+	data := make(map[string]string)
+	declared := make(map[string]bool)
+	for _, p := range q.Params {
+		declared[p.Name] = true
+		v, ok := params[p.Name]
+		if !ok {
+			v = p.Default
+		}
+		if !ok && v == "" && p.Required {
+			return "", fmt.Errorf("query %s: missing required parameter %q", q.Name, p.Name)
+		}
+		data[p.Name] = v
+	}
+	for k := range params {
+		if !declared[k] {
+			return "", fmt.Errorf("query %s: unknown parameter %q", q.Name, k)
+		}
+	}
+
+	tmpl, err := template.New(q.Name).
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{"quote": Quote}).
+		Parse(q.SQL)
+	if err != nil {
+		return "", fmt.Errorf("query %s: %w", q.Name, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("query %s: %w", q.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Quote returns s as a single quoted sql string literal
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,121 @@
+package querylib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = `---
+description: locks held longer than a threshold
+host: pgx_example
+format: csv
+params:
+  - name: minutes
+    default: "5"
+  - name: state
+---
+SELECT pid, state FROM pg_stat_activity
+WHERE now() - query_start > interval '{{.minutes}} minutes'
+{{- if .state}}
+AND state = {{quote .state}}
+{{- end}}
+`
+
+func TestParse(t *testing.T) {
+	q, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Host != "pgx_example" || q.Format != "csv" || len(q.Params) != 2 {
+		t.Errorf("front-matter not parsed: %+v", q)
+	}
+
+	_, err = Parse([]byte("---\nhost: x\nSELECT 1"))
+	if err == nil {
+		t.Error("unterminated front-matter accepted")
+	}
+
+	q, err = Parse([]byte("SELECT 1\n"))
+	if err != nil || q.SQL != "SELECT 1" {
+		t.Errorf("plain query: %q %v", q.SQL, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	q, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := q.Render(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT pid, state FROM pg_stat_activity\nWHERE now() - query_start > interval '5 minutes'"
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	s, err = q.Render(map[string]string{"state": "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want += "\nAND state = 'it''s'"
+	if s != want {
+		t.Errorf("got %q, want %q", s, want)
+	}
+
+	if _, err := q.Render(map[string]string{"bogus": "1"}); err == nil {
+		t.Error("unknown parameter accepted")
+	}
+
+	// an empty value is kept, the default is for an absent one
+	s, err = q.Render(map[string]string{"minutes": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "interval ' minutes'"; !strings.Contains(s, want) {
+		t.Errorf("got %q, want it to contain %q", s, want)
+	}
+
+	q.Params = append(q.Params, Param{Name: "db", Required: true})
+	if _, err := q.Render(nil); err == nil {
+		t.Error("missing required parameter accepted")
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pg", "locks.sql"), []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "count.sql"), []byte("SELECT 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	qq, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qq) != 2 || qq[0].Name != "count" || qq[1].Name != "pg/locks" {
+		t.Errorf("unexpected list: %+v", qq)
+	}
+	q, err := Load(dir, "pg/locks")
+	if err != nil || q.Name != "pg/locks" {
+		t.Errorf("load: %+v %v", q, err)
+	}
+	for _, name := range []string{"../count", "pg/../../count", "/etc/passwd", ""} {
+		if _, err := Load(dir, name); err == nil {
+			t.Errorf("load %q: outside the library accepted", name)
+		}
+	}
+
+	qq, err = List(filepath.Join(dir, "missing"))
+	if err != nil || len(qq) != 0 {
+		t.Errorf("missing dir: %+v %v", qq, err)
+	}
+}
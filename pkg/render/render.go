@@ -0,0 +1,132 @@
+package render
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//########
+// Values
+//########
+
+// binary encodings
+const (
+	BinaryHex    = "hex"
+	BinaryBase64 = "base64"
+)
+
+// ISO 8601 layouts used for date and time columns
+const (
+	DateLayout      = "2006-01-02"
+	TimeLayout      = "15:04:05.999999999"
+	TimestampLayout = "2006-01-02T15:04:05.999999999"
+)
+
+// Renderer formats scanned database values for display
+type Renderer struct {
+	Null   string // text for NULL values
+	Binary string // binary encoding: hex or base64
+}
+
+// Value formats v using the database type name reported by
+// sql.ColumnType.DatabaseTypeName
+func (r Renderer) Value(v any, dbType string) string {
+	dbType = strings.ToUpper(dbType)
+	switch val := v.(type) {
+	case nil:
+		return r.Null
+	case string:
+		return val
+	case []byte:
+		return r.bytes(val, dbType)
+	case [16]byte:
+		return FormatUUID(val[:])
+	case time.Time:
+		return formatTime(val, dbType)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// JSONValue returns v as a json encodable value, keeping NULL,
+// numbers and booleans native
+func (r Renderer) JSONValue(v any, dbType string) any {
+	switch v.(type) {
+	case nil:
+		return nil
+	case int64, float64, float32, bool:
+		return v
+	default:
+		return r.Value(v, dbType)
+	}
+}
+
+func (r Renderer) bytes(b []byte, dbType string) string {
+	switch dbType {
+	case "UNIQUEIDENTIFIER":
+		if len(b) == 16 {
+			return FormatGUID(b)
+		}
+	case "UUID":
+		if len(b) == 16 {
+			return FormatUUID(b)
+		}
+		return string(b)
+	case "BYTEA", "BINARY", "VARBINARY", "IMAGE", "TIMESTAMP", "ROWVERSION", "BLOB":
+	default:
+		// decimals, money, text, xml and json come back as text
+		return string(b)
+	}
+	return r.encode(b)
+}
+
+func (r Renderer) encode(b []byte) string {
+	if r.Binary == BinaryBase64 {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// FormatUUID formats 16 bytes in the canonical 8-4-4-4-12 form
+func FormatUUID(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// FormatGUID formats the 16 bytes of an mssql UNIQUEIDENTIFIER, which
+// stores the first three groups little-endian, in the canonical form
+func FormatGUID(b []byte) string {
+	return FormatUUID([]byte{
+		b[3], b[2], b[1], b[0],
+		b[5], b[4],
+		b[7], b[6],
+		b[8], b[9], b[10], b[11], b[12], b[13], b[14], b[15],
+	})
+}
+
+func formatTime(t time.Time, dbType string) string {
+	switch dbType {
+	case "DATE":
+		return t.Format(DateLayout)
+	case "TIME":
+		return t.Format(TimeLayout)
+	case "TIMESTAMPTZ", "DATETIMEOFFSET":
+		return t.Format(time.RFC3339Nano)
+	case "TIMESTAMP", "DATETIME", "DATETIME2", "SMALLDATETIME":
+		return t.Format(TimestampLayout)
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
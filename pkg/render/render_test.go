@@ -0,0 +1,46 @@
+package render
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	r := Renderer{Null: "NULL", Binary: BinaryHex}
+	ts := time.Date(2024, 3, 9, 14, 5, 7, 120000000, time.UTC)
+	tests := []struct {
+		v      any
+		dbType string
+		want   string
+	}{
+		{nil, "INT4", "NULL"},
+		{int64(42), "INT8", "42"},
+		{0.1, "FLOAT8", "0.1"},
+		{[]byte("12345678901234567890.123456"), "DECIMAL", "12345678901234567890.123456"},
+		{"12345678901234567890.123456", "NUMERIC", "12345678901234567890.123456"},
+		{[]byte{0xde, 0xad, 0xbe, 0xef}, "VARBINARY", "0xdeadbeef"},
+		{[]byte(`{"a":1}`), "JSONB", `{"a":1}`},
+		{
+			[]byte{0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x34, 0x12, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0},
+			"uniqueidentifier",
+			"12345678-1234-1234-1234-56789abcdef0",
+		},
+		{ts, "DATE", "2024-03-09"},
+		{ts, "TIMESTAMP", "2024-03-09T14:05:07.12"},
+		{ts, "TIMESTAMPTZ", "2024-03-09T14:05:07.12Z"},
+		{true, "BOOL", "true"},
+	}
+	for _, tt := range tests {
+		if got := r.Value(tt.v, tt.dbType); got != tt.want {
+			t.Errorf("Value(%v, %s) = %q, want %q", tt.v, tt.dbType, got, tt.want)
+		}
+	}
+
+	r.Binary = BinaryBase64
+	if got := r.Value([]byte{0xde, 0xad, 0xbe, 0xef}, "BYTEA"); got != "3q2+7w==" {
+		t.Errorf("base64 = %q", got)
+	}
+	if got := r.JSONValue(nil, "TEXT"); got != nil {
+		t.Errorf("JSONValue(nil) = %v", got)
+	}
+}
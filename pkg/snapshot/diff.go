@@ -0,0 +1,178 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// change actions
+const (
+	Add   = "+"
+	Drop  = "-"
+	Alter = "~"
+)
+
+// Change difference between two snapshots
+type Change struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Schema string `json:"schema"`
+	Object string `json:"object"`
+	Name   string `json:"name,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	name := c.Schema
+	if c.Object != "" {
+		name += "." + c.Object
+	}
+	if c.Name != "" {
+		name += "." + c.Name
+	}
+	s := fmt.Sprintf("%s %-7s %s", c.Action, c.Kind, name)
+	if c.Action == Alter {
+		s += fmt.Sprintf("  %s -> %s", c.From, c.To)
+	}
+	return s
+}
+
+// Diff returns the changes that turn from into to
+func Diff(from, to *Snapshot) []Change {
+	var cc []Change
+	for _, ts := range to.Schemas {
+		fs, ok := findSchema(from, ts.Name)
+		if !ok {
+			cc = append(cc, Change{Action: Add, Kind: "schema", Schema: ts.Name})
+		}
+		cc = append(cc, diffSchema(fs, ts)...)
+	}
+	for _, fs := range from.Schemas {
+		if _, ok := findSchema(to, fs.Name); !ok {
+			cc = append(cc, Change{Action: Drop, Kind: "schema", Schema: fs.Name})
+			cc = append(cc, diffSchema(fs, Schema{Name: fs.Name})...)
+		}
+	}
+	return cc
+}
+
+// Print writes the changes one per line
+func Print(w io.Writer, cc []Change) {
+	for _, c := range cc {
+		fmt.Fprintln(w, c.String())
+	}
+}
+
+func findSchema(s *Snapshot, name string) (Schema, bool) {
+	for _, ss := range s.Schemas {
+		if ss.Name == name {
+			return ss, true
+		}
+	}
+	return Schema{Name: name}, false
+}
+
+func diffSchema(from, to Schema) []Change {
+	var cc []Change
+	schema := to.Name
+	for _, t := range to.Tables {
+		i := slices.IndexFunc(from.Tables, func(f Table) bool { return f.Name == t.Name })
+		if i < 0 {
+			cc = append(cc, Change{Action: Add, Kind: "table", Schema: schema, Object: t.Name})
+			continue
+		}
+		cc = append(cc, diffTable(schema, from.Tables[i], t)...)
+	}
+	for _, f := range from.Tables {
+		if !slices.ContainsFunc(to.Tables, func(t Table) bool { return t.Name == f.Name }) {
+			cc = append(cc, Change{Action: Drop, Kind: "table", Schema: schema, Object: f.Name})
+		}
+	}
+
+	for _, v := range to.Views {
+		i := slices.IndexFunc(from.Views, func(f View) bool { return f.Name == v.Name })
+		switch {
+		case i < 0:
+			cc = append(cc, Change{Action: Add, Kind: "view", Schema: schema, Object: v.Name})
+		case normalize(from.Views[i].Definition) != normalize(v.Definition):
+			cc = append(cc, Change{Action: Alter, Kind: "view", Schema: schema, Object: v.Name, From: "definition", To: "definition"})
+		}
+	}
+	for _, f := range from.Views {
+		if !slices.ContainsFunc(to.Views, func(v View) bool { return v.Name == f.Name }) {
+			cc = append(cc, Change{Action: Drop, Kind: "view", Schema: schema, Object: f.Name})
+		}
+	}
+
+	for _, r := range to.Routines {
+		i := slices.IndexFunc(from.Routines, func(f Routine) bool { return f.Name == r.Name })
+		switch {
+		case i < 0:
+			cc = append(cc, Change{Action: Add, Kind: "routine", Schema: schema, Object: r.Name})
+		case normalize(from.Routines[i].Definition) != normalize(r.Definition):
+			cc = append(cc, Change{Action: Alter, Kind: "routine", Schema: schema, Object: r.Name, From: "definition", To: "definition"})
+		}
+	}
+	for _, f := range from.Routines {
+		if !slices.ContainsFunc(to.Routines, func(r Routine) bool { return r.Name == f.Name }) {
+			cc = append(cc, Change{Action: Drop, Kind: "routine", Schema: schema, Object: f.Name})
+		}
+	}
+	return cc
+}
+
+func diffTable(schema string, from, to Table) []Change {
+	var cc []Change
+	for _, c := range to.Columns {
+		i := slices.IndexFunc(from.Columns, func(f Column) bool { return f.Name == c.Name })
+		if i < 0 {
+			cc = append(cc, Change{Action: Add, Kind: "column", Schema: schema, Object: to.Name, Name: c.Name, To: c.definition()})
+			continue
+		}
+		if f := from.Columns[i]; f.definition() != c.definition() {
+			cc = append(cc, Change{Action: Alter, Kind: "column", Schema: schema, Object: to.Name, Name: c.Name, From: f.definition(), To: c.definition()})
+		}
+	}
+	for _, f := range from.Columns {
+		if !slices.ContainsFunc(to.Columns, func(c Column) bool { return c.Name == f.Name }) {
+			cc = append(cc, Change{Action: Drop, Kind: "column", Schema: schema, Object: to.Name, Name: f.Name, From: f.definition()})
+		}
+	}
+
+	if !slices.Equal(from.PKey, to.PKey) {
+		cc = append(cc, Change{Action: Alter, Kind: "pkey", Schema: schema, Object: to.Name,
+			From: "(" + strings.Join(from.PKey, ",") + ")", To: "(" + strings.Join(to.PKey, ",") + ")"})
+	}
+
+	// indexes are compared by columns, names are generated on copy
+	for _, i := range to.Indexes {
+		if !slices.ContainsFunc(from.Indexes, func(f Index) bool { return f.Columns == i.Columns }) {
+			cc = append(cc, Change{Action: Add, Kind: "index", Schema: schema, Object: to.Name, Name: i.Name, To: i.Columns})
+		}
+	}
+	for _, f := range from.Indexes {
+		if !slices.ContainsFunc(to.Indexes, func(i Index) bool { return i.Columns == f.Columns }) {
+			cc = append(cc, Change{Action: Drop, Kind: "index", Schema: schema, Object: to.Name, Name: f.Name, From: f.Columns})
+		}
+	}
+	return cc
+}
+
+func (c Column) definition() string {
+	s := c.DataType
+	if !c.Nullable {
+		s += " NOT NULL"
+	}
+	if c.Default != "" {
+		s += " DEFAULT " + c.Default
+	}
+	return s
+}
+
+// normalize collapses whitespace so formatting differences are ignored
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,54 @@
+package snapshot
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := &Snapshot{Schemas: []Schema{{
+		Name: "public",
+		Tables: []Table{
+			{Name: "orders", PKey: []string{"id"}, Columns: []Column{
+				{Name: "id", DataType: "INT"},
+				{Name: "note", DataType: "VARCHAR(10)", Nullable: true},
+				{Name: "old", DataType: "INT", Nullable: true},
+			}},
+			{Name: "gone", Columns: []Column{{Name: "id", DataType: "INT"}}},
+		},
+		Views: []View{{Name: "v", Definition: "SELECT 1"}},
+	}}}
+	to := &Snapshot{Schemas: []Schema{
+		{
+			Name: "public",
+			Tables: []Table{
+				{Name: "orders", PKey: []string{"id"}, Columns: []Column{
+					{Name: "id", DataType: "INT"},
+					{Name: "note", DataType: "VARCHAR(20)", Nullable: true},
+				}, Indexes: []Index{{Name: "orders_note_idx", Columns: `"note"`}}},
+			},
+			Views: []View{{Name: "v", Definition: "SELECT\n  1"}},
+		},
+		{Name: "audit", Tables: []Table{{Name: "log"}}},
+	}}
+
+	got := map[string]bool{}
+	for _, c := range Diff(from, to) {
+		got[c.String()] = true
+	}
+	want := []string{
+		"~ column  public.orders.note  VARCHAR(10) -> VARCHAR(20)",
+		"- column  public.orders.old",
+		"+ index   public.orders.orders_note_idx",
+		"- table   public.gone",
+		"+ schema  audit",
+		"+ table   audit.log",
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing change %q", w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d changes, want %d: %v", len(got), len(want), got)
+	}
+}
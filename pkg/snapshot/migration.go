@@ -0,0 +1,233 @@
+package snapshot
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//########
+// Migration
+//########
+
+// changeRank order the changes are applied in: new schemas first, views and
+// routines dropped before the tables they read and created after them
+func changeRank(c Change) int {
+	switch {
+	case c.Kind == "schema" && c.Action == Add:
+		return 0
+	case (c.Kind == "view" || c.Kind == "routine") && c.Action == Drop:
+		return 1
+	case c.Kind == "index" && c.Action == Drop:
+		return 2
+	case c.Kind == "table" && c.Action == Drop:
+		return 3
+	case c.Kind == "table":
+		return 4
+	case c.Kind == "column" || c.Kind == "pkey":
+		return 5
+	case c.Kind == "index":
+		return 6
+	case c.Kind == "view" || c.Kind == "routine":
+		return 7
+	}
+	return 8
+}
+
+// MigrationSQL returns the statements applying the changes cc that turn
+// from into to, in the dialect of driver. mssql statements are separated by
+// GO lines. What a snapshot does not hold enough to generate is left as a
+// comment to finish by hand.
+func MigrationSQL(driver string, from, to *Snapshot, cc []Change) string {
+	cc = slices.Clone(cc)
+	slices.SortStableFunc(cc, func(a, b Change) int { return cmp.Compare(changeRank(a), changeRank(b)) })
+	var stmts []string
+	for _, c := range cc {
+		stmts = append(stmts, changeSQL(driver, from, to, c)...)
+	}
+	if len(stmts) == 0 {
+		return ""
+	}
+	if driver == "mssql" {
+		return strings.Join(stmts, "\nGO\n") + "\nGO\n"
+	}
+	return strings.Join(stmts, "\n") + "\n"
+}
+
+// changeSQL statements of one change
+func changeSQL(driver string, from, to *Snapshot, c Change) []string {
+	mssql := driver == "mssql"
+	table := fmt.Sprintf(`"%s"."%s"`, c.Schema, c.Object)
+	switch c.Kind + c.Action {
+	case "schema" + Add:
+		return []string{fmt.Sprintf(`CREATE SCHEMA "%s";`, c.Schema)}
+	case "schema" + Drop:
+		return []string{fmt.Sprintf(`DROP SCHEMA "%s";`, c.Schema)}
+
+	case "table" + Add:
+		t := findTable(to, c.Schema, c.Object)
+		stmts := []string{createTable(c.Schema, t)}
+		for _, i := range t.Indexes {
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX "%s" ON %s (%s);`, i.Name, table, i.Columns))
+		}
+		return stmts
+	case "table" + Drop:
+		return []string{fmt.Sprintf(`DROP TABLE %s;`, table)}
+
+	case "column" + Add:
+		return []string{fmt.Sprintf(`ALTER TABLE %s ADD "%s" %s;`, table, c.Name, c.To)}
+	case "column" + Drop:
+		return []string{fmt.Sprintf(`ALTER TABLE %s DROP COLUMN "%s";`, table, c.Name)}
+	case "column" + Alter:
+		return alterColumn(mssql, table, from, to, c)
+
+	case "pkey" + Alter:
+		var stmts []string
+		if c.From != "()" {
+			if mssql {
+				stmts = append(stmts, fmt.Sprintf(`-- drop the primary key %s of %s by hand, mssql names it`, c.From, table))
+			} else {
+				stmts = append(stmts, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT "%s_pkey";`, table, c.Object))
+			}
+		}
+		if c.To != "()" {
+			t := findTable(to, c.Schema, c.Object)
+			stmts = append(stmts, fmt.Sprintf(`ALTER TABLE %s ADD PRIMARY KEY (%s);`, table, quoteList(t.PKey)))
+		}
+		return stmts
+
+	case "index" + Add:
+		return []string{fmt.Sprintf(`CREATE INDEX "%s" ON %s (%s);`, c.Name, table, c.To)}
+	case "index" + Drop:
+		if mssql {
+			return []string{fmt.Sprintf(`DROP INDEX "%s" ON %s;`, c.Name, table)}
+		}
+		return []string{fmt.Sprintf(`DROP INDEX "%s"."%s";`, c.Schema, c.Name)}
+
+	case "view" + Add, "view" + Alter:
+		v := findView(to, c.Schema, c.Object)
+		if mssql {
+			// the definition is the whole create statement
+			return []string{fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, table), strings.TrimSpace(v.Definition)}
+		}
+		create := fmt.Sprintf("CREATE VIEW %s AS\n%s;", table, strings.TrimSuffix(strings.TrimSpace(v.Definition), ";"))
+		if c.Action == Alter {
+			// CREATE OR REPLACE cannot drop, rename or retype columns
+			return []string{fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, table), create}
+		}
+		return []string{create}
+	case "view" + Drop:
+		return []string{fmt.Sprintf(`DROP VIEW %s;`, table)}
+
+	case "routine" + Add, "routine" + Alter:
+		r := findRoutine(to, c.Schema, c.Object)
+		if mssql {
+			return []string{fmt.Sprintf(`DROP %s IF EXISTS %s;`, strings.ToUpper(r.Type), table), strings.TrimSpace(r.Definition)}
+		}
+		return []string{fmt.Sprintf(`-- create or replace %s %s by hand, the snapshot has its body but not its arguments or language`,
+			strings.ToLower(r.Type), table)}
+	case "routine" + Drop:
+		r := findRoutine(from, c.Schema, c.Object)
+		return []string{fmt.Sprintf(`DROP %s %s;`, strings.ToUpper(cmp.Or(r.Type, "FUNCTION")), table)}
+	}
+	return []string{fmt.Sprintf("-- %s", c)}
+}
+
+// alterColumn statements changing the type, nullability and default of a
+// column
+func alterColumn(mssql bool, table string, from, to *Snapshot, c Change) []string {
+	fc := findColumn(from, c.Schema, c.Object, c.Name)
+	tc := findColumn(to, c.Schema, c.Object, c.Name)
+	col := fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN "%s"`, table, c.Name)
+	var stmts []string
+	if mssql {
+		if fc.DataType != tc.DataType || fc.Nullable != tc.Nullable {
+			null := " NULL"
+			if !tc.Nullable {
+				null = " NOT NULL"
+			}
+			stmts = append(stmts, col+" "+tc.DataType+null+";")
+		}
+		if fc.Default != tc.Default {
+			stmts = append(stmts, fmt.Sprintf(`-- change the default of %s."%s" to %q by hand, mssql defaults are named constraints`,
+				table, c.Name, tc.Default))
+		}
+		return stmts
+	}
+	if fc.DataType != tc.DataType {
+		stmts = append(stmts, col+" TYPE "+tc.DataType+";")
+	}
+	if fc.Nullable != tc.Nullable {
+		if tc.Nullable {
+			stmts = append(stmts, col+" DROP NOT NULL;")
+		} else {
+			stmts = append(stmts, col+" SET NOT NULL;")
+		}
+	}
+	if fc.Default != tc.Default {
+		if tc.Default == "" {
+			stmts = append(stmts, col+" DROP DEFAULT;")
+		} else {
+			stmts = append(stmts, col+" SET DEFAULT "+tc.Default+";")
+		}
+	}
+	return stmts
+}
+
+// createTable create statement of table t
+func createTable(schema string, t Table) string {
+	var lines []string
+	for _, c := range t.Columns {
+		lines = append(lines, fmt.Sprintf(`"%s" %s`, c.Name, c.definition()))
+	}
+	if len(t.PKey) > 0 {
+		lines = append(lines, "PRIMARY KEY ("+quoteList(t.PKey)+")")
+	}
+	return fmt.Sprintf("CREATE TABLE \"%s\".\"%s\" (\n%s\n);", schema, t.Name, strings.Join(lines, ",\n"))
+}
+
+// quoteList comma separated quoted names
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = `"` + n + `"`
+	}
+	return strings.Join(quoted, ",")
+}
+
+func findTable(s *Snapshot, schema, name string) Table {
+	ss, _ := findSchema(s, schema)
+	i := slices.IndexFunc(ss.Tables, func(t Table) bool { return t.Name == name })
+	if i < 0 {
+		return Table{Name: name}
+	}
+	return ss.Tables[i]
+}
+
+func findColumn(s *Snapshot, schema, table, name string) Column {
+	t := findTable(s, schema, table)
+	i := slices.IndexFunc(t.Columns, func(c Column) bool { return c.Name == name })
+	if i < 0 {
+		return Column{Name: name}
+	}
+	return t.Columns[i]
+}
+
+func findView(s *Snapshot, schema, name string) View {
+	ss, _ := findSchema(s, schema)
+	i := slices.IndexFunc(ss.Views, func(v View) bool { return v.Name == name })
+	if i < 0 {
+		return View{Name: name}
+	}
+	return ss.Views[i]
+}
+
+func findRoutine(s *Snapshot, schema, name string) Routine {
+	ss, _ := findSchema(s, schema)
+	i := slices.IndexFunc(ss.Routines, func(r Routine) bool { return r.Name == name })
+	if i < 0 {
+		return Routine{Name: name}
+	}
+	return ss.Routines[i]
+}
@@ -0,0 +1,67 @@
+package snapshot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrationSQL(t *testing.T) {
+	from := &Snapshot{Schemas: []Schema{{
+		Name: "public",
+		Tables: []Table{
+			{Name: "orders", PKey: []string{"id"}, Columns: []Column{
+				{Name: "id", DataType: "integer"},
+				{Name: "note", DataType: "varchar(10)", Nullable: true},
+			}},
+		},
+		Views: []View{{Name: "v", Definition: "SELECT id FROM orders;"}},
+	}}}
+	to := &Snapshot{Schemas: []Schema{{
+		Name: "public",
+		Tables: []Table{
+			{Name: "orders", PKey: []string{"id"}, Columns: []Column{
+				{Name: "id", DataType: "integer"},
+				{Name: "note", DataType: "varchar(20)", Default: "''"},
+			}},
+			{Name: "items", PKey: []string{"id"}, Columns: []Column{{Name: "id", DataType: "integer"}},
+				Indexes: []Index{{Name: "items_id_idx", Columns: `"id"`}}},
+		},
+	}}}
+
+	up := MigrationSQL("postgres", from, to, Diff(from, to))
+	want := `DROP VIEW "public"."v";
+CREATE TABLE "public"."items" (
+"id" integer NOT NULL,
+PRIMARY KEY ("id")
+);
+CREATE INDEX "items_id_idx" ON "public"."items" ("id");
+ALTER TABLE "public"."orders" ALTER COLUMN "note" TYPE varchar(20);
+ALTER TABLE "public"."orders" ALTER COLUMN "note" SET NOT NULL;
+ALTER TABLE "public"."orders" ALTER COLUMN "note" SET DEFAULT '';
+`
+	if up != want {
+		t.Errorf("up\n%s\nwant\n%s", up, want)
+	}
+
+	down := MigrationSQL("postgres", to, from, Diff(to, from))
+	if !strings.HasPrefix(down, `DROP TABLE "public"."items";`) || !strings.HasSuffix(down, "CREATE VIEW \"public\".\"v\" AS\nSELECT id FROM orders;\n") {
+		t.Errorf("down\n%s", down)
+	}
+
+	mssql := MigrationSQL("mssql", from, to, Diff(from, to))
+	if !strings.Contains(mssql, "ALTER TABLE \"public\".\"orders\" ALTER COLUMN \"note\" varchar(20) NOT NULL;\nGO\n-- change the default") {
+		t.Errorf("mssql\n%s", mssql)
+	}
+}
+
+func TestMigrationSQLAlterView(t *testing.T) {
+	view := func(def string) *Snapshot {
+		return &Snapshot{Schemas: []Schema{{Name: "public", Views: []View{{Name: "v", Definition: def}}}}}
+	}
+	from, to := view("SELECT id FROM orders;"), view("SELECT id, note FROM orders;")
+	up := MigrationSQL("pgx", from, to, Diff(from, to))
+	want := "DROP VIEW IF EXISTS \"public\".\"v\";\nCREATE VIEW \"public\".\"v\" AS\nSELECT id, note FROM orders;\n"
+	if up != want {
+		t.Errorf("up\n%s\nwant\n%s", up, want)
+	}
+}
@@ -0,0 +1,217 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ppreeper/dbtools/pkg/database"
+)
+
+//########
+// Snapshot
+//########
+
+// Snapshot catalog of a database at a point in time
+type Snapshot struct {
+	Host     string    `json:"host"`
+	Driver   string    `json:"driver"`
+	Database string    `json:"database"`
+	Taken    time.Time `json:"taken"`
+	Schemas  []Schema  `json:"schemas"`
+}
+
+// Schema objects in a schema
+type Schema struct {
+	Name     string    `json:"name"`
+	Tables   []Table   `json:"tables,omitempty"`
+	Views    []View    `json:"views,omitempty"`
+	Routines []Routine `json:"routines,omitempty"`
+}
+
+// Table table definition
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	PKey    []string `json:"pkey,omitempty"`
+	Indexes []Index  `json:"indexes,omitempty"`
+}
+
+// Column table column
+type Column struct {
+	Name     string `json:"name"`
+	DataType string `json:"data_type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+}
+
+// Index table index
+type Index struct {
+	Name    string `json:"name"`
+	Columns string `json:"columns"`
+}
+
+// View view definition
+type View struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// Routine procedure or function definition
+type Routine struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Definition string `json:"definition"`
+}
+
+// Take reads the catalog of db, limited to schema when not empty. Every
+// object is read within timeout, no limit when it is zero.
+func Take(ctx context.Context, db *database.Database, host, schema string, timeout time.Duration) (*Snapshot, error) {
+	snap := &Snapshot{
+		Host:     host,
+		Driver:   db.Driver,
+		Database: db.Database,
+		Taken:    time.Now().UTC(),
+	}
+	qctx, cancel := bounded(ctx, timeout)
+	schemas, err := db.GetSchemas(qctx)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	for _, s := range schemas {
+		if schema != "" && s.Name != schema {
+			continue
+		}
+		ss, err := takeSchema(ctx, db, s.Name, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot: %w", err)
+		}
+		snap.Schemas = append(snap.Schemas, ss)
+	}
+	if schema != "" && len(snap.Schemas) == 0 {
+		return nil, fmt.Errorf("snapshot: schema %s not found", schema)
+	}
+	return snap, nil
+}
+
+// bounded ctx limited to timeout when it is set
+func bounded(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func takeSchema(ctx context.Context, db *database.Database, schema string, timeout time.Duration) (Schema, error) {
+	c := &database.Conn{Source: db, Dest: db, SSchema: schema, DSchema: schema}
+	ss := Schema{Name: schema}
+
+	qctx, cancel := bounded(ctx, timeout)
+	tables, err := c.GetTables(qctx, schema, "BASE TABLE")
+	cancel()
+	if err != nil {
+		return ss, err
+	}
+	for _, t := range tables {
+		qctx, cancel := bounded(ctx, timeout)
+		tt, err := takeTable(qctx, c, t.Name)
+		cancel()
+		if err != nil {
+			return ss, err
+		}
+		ss.Tables = append(ss.Tables, tt)
+	}
+
+	qctx, cancel = bounded(ctx, timeout)
+	views, err := c.GetViews(qctx, schema)
+	cancel()
+	if err != nil {
+		return ss, err
+	}
+	for _, v := range views {
+		qctx, cancel := bounded(ctx, timeout)
+		vv, err := c.GetViewSchema(qctx, schema, v.Name)
+		cancel()
+		if err != nil {
+			return ss, err
+		}
+		ss.Views = append(ss.Views, View{Name: vv.Name, Definition: vv.Definition})
+	}
+
+	qctx, cancel = bounded(ctx, timeout)
+	routines, err := c.GetRoutines(qctx, schema)
+	cancel()
+	if err != nil {
+		return ss, err
+	}
+	for _, r := range routines {
+		qctx, cancel := bounded(ctx, timeout)
+		rr, err := c.GetRoutineSchema(qctx, schema, r.Name)
+		cancel()
+		if err != nil {
+			return ss, err
+		}
+		ss.Routines = append(ss.Routines, Routine{Name: rr.Name, Type: rr.Type, Definition: rr.Definition})
+	}
+	return ss, nil
+}
+
+// takeTable reads the columns, primary key and indexes of a table
+func takeTable(ctx context.Context, c *database.Conn, name string) (Table, error) {
+	tt := Table{Name: name}
+	cols, err := c.GetColumnDetail(ctx, name)
+	if err != nil {
+		return tt, err
+	}
+	for _, col := range cols {
+		tt.Columns = append(tt.Columns, Column{
+			Name:     col.ColumnName,
+			DataType: col.DataType,
+			Nullable: col.IsNullable == "",
+			Default:  col.ColumnDefault,
+		})
+	}
+	pkey, err := c.GetPKey(ctx, name)
+	if err != nil {
+		return tt, err
+	}
+	for _, p := range pkey {
+		tt.PKey = append(tt.PKey, p.PKey)
+	}
+	idxs, err := c.GetTableIndexSchema(ctx, name)
+	if err != nil {
+		return tt, err
+	}
+	for _, i := range idxs {
+		tt.Indexes = append(tt.Indexes, Index{Name: i.Name, Columns: i.Columns})
+	}
+	return tt, nil
+}
+
+// Load reads a snapshot file
+func Load(fn string) (*Snapshot, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot: %w", err)
+	}
+	snap := &Snapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", fn, err)
+	}
+	return snap, nil
+}
+
+// Save writes the snapshot to fn
+func (s *Snapshot) Save(fn string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := os.WriteFile(fn, append(data, '\n'), 0o666); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	return nil
+}